@@ -0,0 +1,98 @@
+package enclave
+
+import (
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
+)
+
+// go-enclaveapi only models a summary of each system, without the virtual address,
+// so these are the system models from the enclave api docs
+
+// An enrolled system as returned by /systems and /systems/{systemId}. Systems waiting
+// for approval under /unapproved-systems have the same shape, less the virtual address.
+type enrolledSystem struct {
+	SystemId       enclaveSystem.SystemId
+	Description    string
+	Notes          string
+	Type           string
+	State          enclaveSystem.SystemState
+	EnrolmentKeyId enclaveEnrolmentKey.EnrolmentKeyId
+	IsEnabled      bool
+	Hostname       string
+	PlatformType   string
+	OSVersion      string
+	EnclaveVersion string
+	VirtualAddress string
+	Tags           []enclaveTag.TagReference
+}
+
+// The changes to make to a system, fields left nil are not changed. Unlike the go-enclaveapi
+// patches this means empty values can be sent to clear a field.
+type enrolledSystemPatch struct {
+	Description *string   `json:",omitempty"`
+	Notes       *string   `json:",omitempty"`
+	Tags        *[]string `json:",omitempty"`
+}
+
+// The go-enclaveapi patches leave out empty values, so a field can't be cleared through them.
+// These send every field the provider manages, with empty values clearing it, while fields
+// left nil are still not changed.
+
+// The changes to make to a policy
+type policyPatch struct {
+	Description       *string                                       `json:",omitempty"`
+	Notes             *string                                       `json:",omitempty"`
+	SenderTags        *[]string                                     `json:",omitempty"`
+	ReceiverTags      *[]string                                     `json:",omitempty"`
+	Acls              *[]enclavePolicy.PolicyAcl                    `json:",omitempty"`
+	TrustRequirements *[]enclaveTrustRequirement.TrustRequirementId `json:",omitempty"`
+}
+
+// The changes to make to a tag
+type tagPatch struct {
+	Tag               *string                                       `json:",omitempty"`
+	Colour            *string                                       `json:",omitempty"`
+	Notes             *string                                       `json:",omitempty"`
+	TrustRequirements *[]enclaveTrustRequirement.TrustRequirementId `json:",omitempty"`
+}
+
+// The changes to make to a dns zone
+type dnsZonePatch struct {
+	Name  *string `json:",omitempty"`
+	Notes *string `json:",omitempty"`
+}
+
+// The changes to make to a dns record
+type dnsRecordPatch struct {
+	Name    *string   `json:",omitempty"`
+	Tags    *[]string `json:",omitempty"`
+	Systems *[]string `json:",omitempty"`
+	Notes   *string   `json:",omitempty"`
+}
+
+// The changes to make to a trust requirement
+type trustRequirementPatch struct {
+	Description *string                                           `json:",omitempty"`
+	Notes       *string                                           `json:",omitempty"`
+	Settings    *enclaveTrustRequirement.TrustRequirementSettings `json:",omitempty"`
+}
+
+// The changes to make to an enrolment key
+type enrolmentKeyPatch struct {
+	Description                  *string                                       `json:",omitempty"`
+	ApprovalMode                 *enclaveEnrolmentKey.EnrolmentKeyApprovalMode `json:",omitempty"`
+	Tags                         *[]string                                     `json:",omitempty"`
+	DisconnectedRetentionMinutes *int                                          `json:",omitempty"`
+}
+
+// A list to send in a patch, empty rather than nil so the api clears it instead of leaving it alone
+func patchList[T any](values []T) *[]T {
+	if values == nil {
+		values = []T{}
+	}
+
+	return &values
+}
@@ -0,0 +1,388 @@
+package enclave
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	enclaveData "github.com/enclave-networks/go-enclaveapi/data"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultRequestTimeout = time.Minute
+)
+
+// The delay before the first retry, doubling for each retry after it up to retryMaxDelay.
+// Vars so tests don't have to wait as long.
+var (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// Create an api client whose requests go through our transport. Failed requests are retried up to
+// maxRetries times, and each attempt is given up on after requestTimeout.
+func newApiClient(token string, baseUrl string, maxRetries int, requestTimeout time.Duration) (*apiClient, error) {
+	if _, err := url.Parse(baseUrl); err != nil {
+		return nil, err
+	}
+
+	// the timeout is applied to each attempt by the transport rather than to every retry together
+	httpClient := &http.Client{
+		Transport: &transport{
+			next:           &loggingTransport{next: http.DefaultTransport},
+			maxRetries:     maxRetries,
+			requestTimeout: requestTimeout,
+		},
+	}
+
+	return &apiClient{
+		httpClient: httpClient,
+		token:      token,
+		baseUrl:    baseUrl,
+	}, nil
+}
+
+// Where the transport records how many attempts it made at a request
+type attemptCountKey struct{}
+
+// Get a context for a request that has the transport count its attempts at it in count
+func withAttemptCount(ctx context.Context, count *int) context.Context {
+	return context.WithValue(ctx, attemptCountKey{}, count)
+}
+
+// Wraps the default transport, retrying requests that fail in a way that might not happen again.
+// Like any http.RoundTripper an unsuccessful response is returned as it is rather than as an error,
+// the number of attempts made at it is counted in the request's context if it has withAttemptCount.
+type transport struct {
+	next           http.RoundTripper
+	maxRetries     int
+	requestTimeout time.Duration
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	count, _ := req.Context().Value(attemptCountKey{}).(*int)
+
+	for attempt := 1; ; attempt++ {
+		if count != nil {
+			*count = attempt
+		}
+
+		if attempt > 1 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		response, err := t.send(req)
+		if err == nil && isSuccessStatusCode(response.StatusCode) {
+			return response, nil
+		}
+
+		var delay time.Duration
+		retry := false
+		if err != nil {
+			// the request may have got through before the connection failed, so only retry when that's harmless
+			retry = isIdempotent(req.Method) && req.Context().Err() == nil
+		} else {
+			retry, delay = shouldRetry(req, response)
+		}
+
+		if !retry || attempt > t.maxRetries {
+			if err != nil {
+				return nil, withAttempts(err, attempt)
+			}
+
+			return response, nil
+		}
+
+		if err == nil {
+			// the response is given up on for the retry
+			err = readApiError(response)
+		}
+
+		if delay == 0 {
+			delay = backoff(attempt)
+		}
+
+		tflog.Debug(req.Context(), "Retrying Enclave API request", map[string]interface{}{
+			"method":  req.Method,
+			"path":    req.URL.Path,
+			"attempt": attempt + 1,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+
+		select {
+		case <-req.Context().Done():
+			return nil, withAttempts(req.Context().Err(), attempt)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Make a single attempt at a request, giving up after the request timeout
+func (t *transport) send(req *http.Request) (*http.Response, error) {
+	if t.requestTimeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.requestTimeout)
+
+	response, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && req.Context().Err() == nil {
+			err = fmt.Errorf("no response after %s: %w", t.requestTimeout, err)
+		}
+
+		cancel()
+		return nil, err
+	}
+
+	// the timeout has to last until the body has been read
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// Check if an unsuccessful response is worth retrying, along with how long the api asked us to wait
+func shouldRetry(req *http.Request, response *http.Response) (bool, time.Duration) {
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// the request wasn't handled so it's safe to send again whatever it does
+		return true, retryAfter(response.Header.Get("Retry-After"))
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method), 0
+	}
+
+	return false, 0
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// Parse a Retry-After header, which is either a number of seconds or a date. Zero if it's missing or invalid.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil && time.Until(date) > 0 {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// The delay before retrying after an attempt, exponential with some jitter so parallel
+// requests don't all retry at once
+func backoff(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 32 && retryBaseDelay<<(attempt-1) < retryMaxDelay {
+		delay = retryBaseDelay << (attempt - 1)
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func isSuccessStatusCode(statusCode int) bool {
+	return statusCode >= 200 && statusCode <= 299
+}
+
+// Read an unsuccessful response into an *apiError
+func readApiError(response *http.Response) error {
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	return parseApiError(response.StatusCode, body)
+}
+
+// Parse the body of an unsuccessful response, a problem details body with errors for each field of the
+// request if it didn't pass validation
+func parseApiError(statusCode int, body []byte) *apiError {
+	apiErr := &apiError{
+		StatusCode: statusCode,
+	}
+
+	var problem struct {
+		Title  string
+		Detail string
+		Errors map[string][]string
+	}
+	if json.Unmarshal(body, &problem) == nil {
+		apiErr.Title = problem.Title
+		apiErr.Detail = problem.Detail
+		apiErr.Errors = problem.Errors
+	}
+
+	return apiErr
+}
+
+// Record how many attempts were made on the error for a request
+func withAttempts(err error, attempts int) error {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		apiErr.Attempts = attempts
+		return err
+	}
+
+	if attempts == 1 {
+		return err
+	}
+
+	return &retriedError{Attempts: attempts, Err: err}
+}
+
+// Makes every call to the enclave api, through the transport that retries and logs them.
+// go-enclaveapi's list calls never send their query string and its errors for unsuccessful
+// responses are plain text, so only its data types are used.
+type apiClient struct {
+	httpClient *http.Client
+	token      string
+	baseUrl    string
+	org        enclaveData.AccountOrganisation
+
+	// Requests are cancelled along with ctx if it's set
+	ctx context.Context
+}
+
+// Get every item from a paginated org endpoint such as /policies
+func listAll[T any](a *apiClient, route string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("per_page", fmt.Sprint(listPageSize))
+
+	var items []T
+	for {
+		var page enclaveData.PaginatedResponse[T]
+		if err := a.get(route, query, &page); err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+		if len(page.Items) == 0 || len(items) >= page.Metadata.Total {
+			return items, nil
+		}
+
+		// the last page points at itself
+		nextPage := fmt.Sprint(page.Metadata.NextPage)
+		if nextPage == query.Get("page") {
+			return items, nil
+		}
+
+		query.Set("page", nextPage)
+	}
+}
+
+// Make a GET request against the current org and decode the response into result
+func (a *apiClient) get(route string, query url.Values, result any) error {
+	return a.do(http.MethodGet, route, query, nil, result)
+}
+
+// Get the organisations the token has access to, the only call that isn't made against an org
+func (a *apiClient) getOrgs() ([]enclaveData.AccountOrganisation, error) {
+	var orgs enclaveData.AccountOrganisationTopLevel
+	if err := a.request(http.MethodGet, "account/orgs", nil, nil, &orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs.Orgs, nil
+}
+
+// Make a request against the current org, sending body as json if it's set and decoding
+// the response into result if it's set
+func (a *apiClient) do(method string, route string, query url.Values, body any, result any) error {
+	return a.request(method, fmt.Sprintf("org/%s%s", a.org.OrgId, route), query, body, result)
+}
+
+// Make a request to a path relative to the api's base url. An unsuccessful response is returned as
+// an *apiError, which is the one place they're turned into errors.
+func (a *apiClient) request(method string, path string, query url.Values, body any, result any) error {
+	baseUrl := a.baseUrl
+	if baseUrl == "" {
+		baseUrl = enclaveData.Scheme + "://" + enclaveData.BaseUrl
+	}
+
+	reqUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return err
+	}
+
+	reqUrl = reqUrl.ResolveReference(&url.URL{Path: path})
+	reqUrl.RawQuery = query.Encode()
+
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attempts := 0
+	req, err := http.NewRequestWithContext(withAttemptCount(ctx, &attempts), method, reqUrl.String(), reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	req.Header.Set("User-Agent", "terraform-provider-enclave")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if !isSuccessStatusCode(response.StatusCode) {
+		return withAttempts(readApiError(response), attempts)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
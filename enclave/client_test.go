@@ -0,0 +1,344 @@
+package enclave
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+// A server that responds with each status in turn, then 200 for every request after them
+type statusServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	headers  http.Header
+	bodies   []string
+}
+
+func newStatusServer(t *testing.T, statuses ...int) *statusServer {
+	s := &statusServer{statuses: statuses, headers: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.bodies = append(s.bodies, string(body))
+
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+
+		for name, values := range s.headers {
+			w.Header()[name] = values
+		}
+
+		writeProblem(w, status, http.StatusText(status))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *statusServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.bodies)
+}
+
+func fastRetries(t *testing.T) {
+	base, max := retryBaseDelay, retryMaxDelay
+	retryBaseDelay, retryMaxDelay = time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() {
+		retryBaseDelay, retryMaxDelay = base, max
+	})
+}
+
+func TestTransportRetries(t *testing.T) {
+	fastRetries(t)
+
+	testCases := map[string]struct {
+		method   string
+		statuses []int
+		attempts int
+		error    string
+	}{
+		"success":                     {http.MethodGet, nil, 1, ""},
+		"rate limited":                {http.MethodGet, []int{429, 429}, 3, ""},
+		"unavailable create":          {http.MethodPost, []int{503}, 2, ""},
+		"server error":                {http.MethodDelete, []int{500, 502, 504}, 4, ""},
+		"server error on create":      {http.MethodPost, []int{500}, 1, "response 500 \n"},
+		"server error on update":      {http.MethodPatch, []int{502}, 1, "response 502 \n"},
+		"not found":                   {http.MethodGet, []int{404}, 1, "response 404 \n"},
+		"gives up":                    {http.MethodGet, []int{503, 503, 503, 503, 503}, 4, "response 503 after 4 attempts"},
+		"gives up while rate limited": {http.MethodPut, []int{429, 429, 429, 429}, 4, "response 429 after 4 attempts"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := newStatusServer(t, testCase.statuses...)
+			api := &apiClient{
+				httpClient: &http.Client{Transport: &transport{next: http.DefaultTransport, maxRetries: 3}},
+				token:      mockApiToken,
+				baseUrl:    server.URL,
+			}
+
+			err := api.do(testCase.method, "/tags", nil, map[string]string{"name": "web"}, nil)
+			if testCase.error == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), testCase.error) {
+				t.Fatalf("expected an error containing %q, got %v", testCase.error, err)
+			}
+
+			if server.attempts() != testCase.attempts {
+				t.Errorf("expected %d attempts, got %d", testCase.attempts, server.attempts())
+			}
+
+			// the body has to be sent again with each retry
+			for i, body := range server.bodies {
+				if body != `{"name":"web"}` {
+					t.Errorf("attempt %d: expected the request body, got %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestTransportUnsuccessfulResponse(t *testing.T) {
+	fastRetries(t)
+
+	server := newStatusServer(t, http.StatusServiceUnavailable, http.StatusNotFound)
+	client := &http.Client{Transport: &transport{next: http.DefaultTransport, maxRetries: 3}}
+
+	// like any http.RoundTripper the response is returned as it is, with the attempts counted separately
+	attempts := 0
+	req, err := http.NewRequestWithContext(withAttemptCount(context.Background(), &attempts), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusNotFound || !strings.Contains(string(body), "Not Found") {
+		t.Errorf("expected the 404 response, got %d %s", response.StatusCode, body)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestApiClientError(t *testing.T) {
+	fastRetries(t)
+
+	server := newStatusServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusForbidden)
+
+	api, err := newApiClient(mockApiToken, server.URL, 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = api.getOrgs()
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || apiErr.Title != "Forbidden" || apiErr.Attempts != 3 {
+		t.Fatalf("expected a 403 *apiError after 3 attempts, got %#v", err)
+	}
+
+	// a successful request has no error
+	if _, err := api.getOrgs(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	// the error belongs to the request that failed rather than being shared between them
+	server.statuses = []int{http.StatusUnauthorized}
+	if err := api.get("/tags", nil, nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Attempts != 1 {
+		t.Errorf("expected a 401 *apiError, got %v", err)
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	fastRetries(t)
+
+	server := newStatusServer(t, http.StatusTooManyRequests)
+	server.headers.Set("Retry-After", "1")
+
+	client := &http.Client{Transport: &transport{next: http.DefaultTransport, maxRetries: 3}}
+
+	start := time.Now()
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait for the Retry-After header, retried after %s", elapsed)
+	}
+}
+
+func TestTransportRequestTimeout(t *testing.T) {
+	fastRetries(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &transport{next: http.DefaultTransport, maxRetries: 1, requestTimeout: 20 * time.Millisecond}}
+
+	_, err := client.Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "no response after 20ms") || !strings.Contains(err.Error(), "(after 2 attempts)") {
+		t.Fatalf("expected the request to time out twice, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	testCases := map[string]struct {
+		header   string
+		expected time.Duration
+	}{
+		"missing": {"", 0},
+		"seconds": {"5", 5 * time.Second},
+		"invalid": {"soon", 0},
+		"past":    {"Mon, 02 Jan 2006 15:04:05 GMT", 0},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := retryAfter(testCase.header); actual != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, actual)
+			}
+		})
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if actual := retryAfter(date); actual <= 50*time.Second || actual > time.Minute {
+		t.Errorf("expected about a minute for %s, got %s", date, actual)
+	}
+}
+
+func TestProviderWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	api, err := newApiClient(mockApiToken, server.URL, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	p := provider{configured: true, api: api}.withContext(ctx)
+	if _, err := p.api.getOrgs(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request to be cancelled, got %v", err)
+	}
+
+	if err := p.api.get("/tags", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request to be cancelled, got %v", err)
+	}
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the requests to stop when the context was done, took %s", elapsed)
+	}
+
+	// the provider's own api client is left as it was
+	if api.ctx != nil {
+		t.Errorf("expected the original api client to have no context, got %v", api.ctx)
+	}
+}
+
+func TestLoggingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "request-1")
+		writeJson(w, http.StatusCreated, map[string]any{
+			"id":  1,
+			"key": "secret-enrolment-key",
+			"tags": []any{
+				map[string]any{"tag": "servers"},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/org/abc/enrolment-keys?include_disabled=true", strings.NewReader(`{"description":"servers","key":"secret-request-key"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+mockApiToken)
+
+	client := &http.Client{Transport: &loggingTransport{next: http.DefaultTransport}}
+	response, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the body is still there to be read after it's been logged
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if !strings.Contains(string(body), "secret-enrolment-key") {
+		t.Errorf("expected the response body to be left as it was, got %s", body)
+	}
+
+	for _, secret := range []string{mockApiToken, "secret-enrolment-key", "secret-request-key"} {
+		if strings.Contains(output.String(), secret) {
+			t.Errorf("expected %q to be redacted from the logs, got %s", secret, output.String())
+		}
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string]map[string]interface{}{}
+	for _, entry := range entries {
+		messages[entry["@message"].(string)] = entry
+	}
+
+	sent := messages["Sending Enclave API request"]
+	if sent == nil || sent["method"] != http.MethodPost || sent["path"] != "/org/abc/enrolment-keys" || sent["query"] != "include_disabled=true" {
+		t.Errorf("expected the request to be logged, got %v", sent)
+	}
+
+	received := messages["Received Enclave API response"]
+	if received == nil || received["status"] != float64(http.StatusCreated) || received["request_id"] != "request-1" || received["duration_ms"] == nil {
+		t.Errorf("expected the response to be logged, got %v", received)
+	}
+
+	details := messages["Enclave API response details"]
+	if details == nil || !strings.Contains(details["body"].(string), `"key":"[REDACTED]"`) || !strings.Contains(details["body"].(string), `"tag":"servers"`) {
+		t.Errorf("expected the response body to be logged with its key redacted, got %v", details)
+	}
+}
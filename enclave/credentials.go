@@ -0,0 +1,200 @@
+package enclave
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The profile used when none is set, it's only read if it exists and can be read
+const defaultProfile = "default"
+
+// A provider setting along with where it was read from, so diagnostics can say which source was used
+type providerSetting struct {
+	Value  string
+	Source string
+}
+
+type providerSettings struct {
+	Token            providerSetting
+	OrganisationId   providerSetting
+	OrganisationName providerSetting
+	Url              providerSetting
+}
+
+// Work out each provider setting from, in order: the provider block, a profile named in the provider
+// block or ENCLAVE_PROFILE, the ENCLAVE_* environment variables, then the default profile
+func resolveProviderSettings(config providerData) (providerSettings, diag.Diagnostics) {
+	var settings providerSettings
+	var diags diag.Diagnostics
+
+	profileName := defaultProfile
+	explicitProfile := true
+	if !config.Profile.Null {
+		profileName = config.Profile.Value
+	} else if envProfile := os.Getenv("ENCLAVE_PROFILE"); envProfile != "" {
+		profileName = envProfile
+	} else {
+		explicitProfile = false
+	}
+
+	// the credentials file is only needed for a profile that's been asked for, without one it's
+	// skipped if it can't be found or read, so a broken file can't get in the way of other settings
+	var profile map[string]string
+	credentialsPath, err := credentialsFilePath()
+	if err == nil {
+		profile, err = readCredentialsProfile(credentialsPath, profileName)
+	}
+	if err != nil && explicitProfile {
+		diags.AddAttributeError(
+			tftypes.NewAttributePath().WithAttributeName("profile"),
+			"Unable to read credentials profile",
+			err.Error(),
+		)
+		return settings, diags
+	}
+
+	profileSource := fmt.Sprintf("the %q profile in %s", profileName, credentialsPath)
+
+	// each source in order, returning the setting if the source sets it
+	sources := []func(attribute types.String, name string, envVar string) (providerSetting, bool){
+		func(attribute types.String, name string, _ string) (providerSetting, bool) {
+			return providerSetting{Value: attribute.Value, Source: fmt.Sprintf("the provider %q attribute", name)}, !attribute.Null
+		},
+		func(_ types.String, name string, _ string) (providerSetting, bool) {
+			value, ok := profile[name]
+			return providerSetting{Value: value, Source: profileSource}, ok && explicitProfile
+		},
+		func(_ types.String, _ string, envVar string) (providerSetting, bool) {
+			value := os.Getenv(envVar)
+			return providerSetting{Value: value, Source: fmt.Sprintf("the %s environment variable", envVar)}, value != ""
+		},
+		func(_ types.String, name string, _ string) (providerSetting, bool) {
+			value, ok := profile[name]
+			return providerSetting{Value: value, Source: profileSource}, ok
+		},
+	}
+
+	resolve := func(attribute types.String, name string, envVar string) providerSetting {
+		for _, source := range sources {
+			if setting, ok := source(attribute, name, envVar); ok {
+				return setting
+			}
+		}
+
+		return providerSetting{}
+	}
+
+	settings.Token = resolve(config.Token, "token", "ENCLAVE_TOKEN")
+	settings.Url = resolve(config.Url, "url", "ENCLAVE_URL")
+
+	// the organisation can be set by id or name, but only one of them in the same place
+	for _, source := range sources {
+		id, hasId := source(config.OrganisationId, "organisation_id", "ENCLAVE_ORGANISATION_ID")
+		name, hasName := source(config.OrganisationName, "organisation_name", "ENCLAVE_ORGANISATION_NAME")
+
+		if hasId && hasName {
+			diags.AddAttributeError(
+				tftypes.NewAttributePath().WithAttributeName("organisation_name"),
+				"Conflicting organisation settings",
+				fmt.Sprintf("Only one of organisation_id from %s or organisation_name from %s can be set", id.Source, name.Source),
+			)
+			return settings, diags
+		}
+
+		if hasId {
+			settings.OrganisationId = id
+			break
+		}
+
+		if hasName {
+			settings.OrganisationName = name
+			break
+		}
+	}
+
+	return settings, diags
+}
+
+// The credentials file is ~/.enclave/credentials unless ENCLAVE_CREDENTIALS_FILE is set
+func credentialsFilePath() (string, error) {
+	if path := os.Getenv("ENCLAVE_CREDENTIALS_FILE"); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".enclave", "credentials"), nil
+}
+
+var errProfileNotFound = errors.New("profile not found")
+
+// Read a profile from an ini style credentials file, e.g.
+//
+//	[default]
+//	token = abc123
+//	organisation_id = 123abc
+func readCredentialsProfile(path string, profileName string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: there is no credentials file at %s", errProfileNotFound, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := map[string]map[string]string{}
+	var current map[string]string
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			name := strings.TrimSpace(text[1 : len(text)-1])
+			current = map[string]string{}
+			profiles[name] = current
+			continue
+		}
+
+		key, value, found := strings.Cut(text, "=")
+		if !found || current == nil {
+			return nil, fmt.Errorf("%s line %d: expected a [profile] or key = value", path, line)
+		}
+
+		current[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	profile, ok := profiles[profileName]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("%w: %q is not in %s, the profiles available are: %s", errProfileNotFound, profileName, path, strings.Join(names, ", "))
+	}
+
+	return profile, nil
+}
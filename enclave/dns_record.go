@@ -3,19 +3,24 @@ package enclave
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	enclaveDns "github.com/enclave-networks/go-enclaveapi/data/dns"
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
+// The default enclave zone used when no zone_id is given
+const defaultDnsZoneId = enclaveDns.DnsZoneId(1)
+
 type dnsRecordResourceType struct{}
 
 func (d dnsRecordResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 1,
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
 				Type:     types.Int64Type,
@@ -24,19 +29,25 @@ func (d dnsRecordResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.
 			"zone_id": {
 				Type:     types.Int64Type,
 				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					defaultValue(types.Int64{Value: int64(defaultDnsZoneId)}),
+					// records can't be moved between zones
+					requiresReplace(),
+				},
 			},
 			"name": {
 				Type:     types.StringType,
 				Required: true,
 			},
 			"tags": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.StringType,
 				},
 				Optional: true,
 			},
 			"systems": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.StringType,
 				},
 				Optional: true,
@@ -49,6 +60,10 @@ func (d dnsRecordResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.
 				Type:     types.StringType,
 				Computed: true,
 			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -83,28 +98,32 @@ func (d dnsRecord) Create(ctx context.Context, req tfsdk.CreateResourceRequest,
 		return
 	}
 
-	var zoneId enclaveDns.DnsZoneId
-	if !plan.ZoneId.Null {
-		zoneId = enclaveDns.DnsZoneId(plan.ZoneId.Value)
-	} else {
-		zoneId = enclaveDns.DnsZoneId(1)
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
+	plan.OrganisationId = org.organisationId()
+
 	dnsRecordCreate := enclaveDns.DnsRecordCreate{
 		Name:    plan.Name.Value,
-		ZoneId:  zoneId,
+		ZoneId:  enclaveDns.DnsZoneId(plan.ZoneId.Value),
 		Tags:    plan.Tags,
 		Systems: plan.Systems,
 		Notes:   plan.Notes.Value,
+		// go-enclaveapi used to fill in the type, the api needs it set
+		Type: "ENCLAVE",
 	}
 
 	// create request
-	dnsRecord, err := d.provider.client.Dns.CreateRecord(dnsRecordCreate)
+	var dnsRecord enclaveDns.DnsRecord
+	err := org.api.do(http.MethodPost, "/dns/records", nil, dnsRecordCreate, &dnsRecord)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating dnsRecord in enclave",
-			err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating dnsRecord in enclave", "Could not create DNS record", err)
 		return
 	}
 
@@ -127,15 +146,22 @@ func (d dnsRecord) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest,
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	dnsRecordId := enclaveDns.DnsRecordId(state.Id.Value)
 
 	//call api to delete
-	_, err := d.provider.client.Dns.DeleteRecord(dnsRecordId)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting Dns Record",
-			"Could not read Id "+fmt.Sprint(dnsRecordId)+": "+err.Error(),
-		)
+	err := org.api.do(http.MethodDelete, fmt.Sprintf("/dns/records/%d", dnsRecordId), nil, nil, nil)
+	// already deleted outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error deleting Dns Record", "Could not delete Id "+fmt.Sprint(dnsRecordId), err)
 		return
 	}
 
@@ -152,18 +178,32 @@ func (d dnsRecord) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
 	dnsRecordId := enclaveDns.DnsRecordId(state.Id.Value)
 
-	dnsRecord, err := d.provider.client.Dns.GetRecord(dnsRecordId)
+	var dnsRecord enclaveDns.DnsRecord
+	err := org.api.get(fmt.Sprintf("/dns/records/%d", dnsRecordId), nil, &dnsRecord)
+	if isNotFound(err) {
+		// the dns record has been deleted outside of terraform, remove it so it gets recreated
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error reading Dns Record",
-			"Could not read Id "+fmt.Sprint(dnsRecordId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading Dns Record", "Could not read Id "+fmt.Sprint(dnsRecordId), err)
 		return
 	}
 
-	setDnsRecordState(dnsRecord, &state)
+	setDnsRecordFullState(dnsRecord, &state)
 
 	diags = resp.State.Set(ctx, state)
 	resp.Diagnostics.Append(diags...)
@@ -187,20 +227,28 @@ func (d dnsRecord) Update(ctx context.Context, req tfsdk.UpdateResourceRequest,
 	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
 	dnsRecordId := enclaveDns.DnsRecordId(state.Id.Value)
 
-	updateDnsRecord, err := d.provider.client.Dns.UpdateRecord(dnsRecordId, enclaveDns.DnsRecordPatch{
-		Name:    plan.Name.Value,
-		Tags:    plan.Tags,
-		Systems: plan.Systems,
-		Notes:   plan.Notes.Value,
-	})
+	var updateDnsRecord enclaveDns.DnsRecord
+	err := org.api.do(http.MethodPatch, fmt.Sprintf("/dns/records/%d", dnsRecordId), nil, dnsRecordPatch{
+		Name:    &plan.Name.Value,
+		Tags:    patchList(plan.Tags),
+		Systems: patchList(plan.Systems),
+		Notes:   &plan.Notes.Value,
+	}, &updateDnsRecord)
 
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error updating Dns Record",
-			"Could not read Id "+fmt.Sprint(dnsRecordId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating Dns Record", "Could not update Id "+fmt.Sprint(dnsRecordId), err)
 		return
 	}
 
@@ -213,13 +261,65 @@ func (d dnsRecord) Update(ctx context.Context, req tfsdk.UpdateResourceRequest,
 	}
 }
 
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (d dnsRecord) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, d.provider, req, resp)
+}
+
 // ImportState implements tfsdk.Resource
 func (dnsRecord) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
-	tfsdk.ResourceImportStatePassthroughID(ctx, tftypes.NewAttributePath().WithAttributeName("id"), req, resp)
+	importStateInt64Id(ctx, req, resp)
+}
 
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (d dnsRecord) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		// version 0 had lists of tags and systems, which are now sets so their order doesn't matter
+		stateUpgrade{
+			priorSchema: tfsdk.Schema{
+				Attributes: map[string]tfsdk.Attribute{
+					"id":              {Type: types.Int64Type, Computed: true},
+					"zone_id":         {Type: types.Int64Type, Optional: true, Computed: true},
+					"name":            {Type: types.StringType, Required: true},
+					"tags":            {Type: types.ListType{ElemType: types.StringType}, Optional: true},
+					"systems":         {Type: types.ListType{ElemType: types.StringType}, Optional: true},
+					"notes":           {Type: types.StringType, Optional: true},
+					"fqdn":            {Type: types.StringType, Computed: true},
+					"organisation_id": {Type: types.StringType, Optional: true, Computed: true},
+				},
+				Blocks: map[string]tfsdk.Block{
+					"timeouts": timeoutsBlock(),
+				},
+			},
+			upgrade: listsToSets("tags", "systems"),
+		},
+	)
 }
 
 func setDnsRecordState(dnsRecord enclaveDns.DnsRecord, state *DnsRecordState) {
 	state.Id = types.Int64{Value: int64(dnsRecord.Id)}
 	state.Fqdn = types.String{Value: dnsRecord.Fqdn}
 }
+
+// Map the full api record back into state so out-of-band changes are detected,
+// notes aren't returned by the api so whatever is in state is kept
+func setDnsRecordFullState(dnsRecord enclaveDns.DnsRecord, state *DnsRecordState) {
+	setDnsRecordState(dnsRecord, state)
+	state.Name = types.String{Value: dnsRecord.Name}
+	state.Tags = fromTagReferences(dnsRecord.Tags, state.Tags)
+	state.Systems = fromSystemReferences(dnsRecord.Systems)
+	state.ZoneId = types.Int64{Value: int64(dnsRecord.ZoneId)}
+}
+
+func fromSystemReferences(systems []enclaveSystem.SystemReference) []string {
+	if len(systems) == 0 {
+		return nil
+	}
+
+	output := make([]string, len(systems))
+	for i, system := range systems {
+		output[i] = string(system.Id)
+	}
+
+	return output
+}
@@ -0,0 +1,177 @@
+package enclave
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	enclaveDns "github.com/enclave-networks/go-enclaveapi/data/dns"
+)
+
+func TestDnsRecordResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_dns_record",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"name": "db",
+					"tags": []string{"database"},
+				},
+				expect: map[string]any{
+					"name":    "db",
+					"zone_id": 1,
+					"fqdn":    "db.enclave",
+					"tags":    []string{"database"},
+					"systems": nil,
+				},
+			},
+			{
+				config: map[string]any{
+					"name":    "sql",
+					"tags":    []string{"database", "sql"},
+					"systems": []string{"ABCDE"},
+				},
+				expect: map[string]any{
+					"name":    "sql",
+					"fqdn":    "sql.enclave",
+					"tags":    []string{"database", "sql"},
+					"systems": []string{"ABCDE"},
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					for _, record := range api.dnsRecords {
+						record.Systems = nil
+					}
+				},
+				config: map[string]any{
+					"name":    "sql",
+					"tags":    []string{"database", "sql"},
+					"systems": []string{"ABCDE"},
+				},
+				expect: map[string]any{
+					"systems": []string{"ABCDE"},
+				},
+			},
+			{
+				// removing the systems, and a tag added in the portal, clears them
+				preConfig: func(api *mockApi) {
+					for _, record := range api.dnsRecords {
+						record.Tags = api.tagReferences([]string{"database", "sql", "web"})
+					}
+				},
+				config: map[string]any{
+					"name": "sql",
+					"tags": []string{"database", "sql"},
+				},
+				expect: map[string]any{
+					"tags":    []string{"database", "sql"},
+					"systems": nil,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					record := api.dnsRecords[enclaveDns.DnsRecordId(state["id"].(int64))]
+					if len(record.Tags) != 2 || len(record.Systems) != 0 {
+						t.Errorf("expected the portal tag and the systems to be cleared: %#v", record)
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+		checkDestroy: func(api *mockApi) error {
+			if len(api.dnsRecords) != 0 {
+				return fmt.Errorf("%d records still exist", len(api.dnsRecords))
+			}
+			return nil
+		},
+	})
+}
+
+func TestDnsRecordResourceInZone(t *testing.T) {
+	var replacement replacementCheck
+
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_dns_record",
+		steps: []resourceTestStep{
+			{
+				preConfig: func(api *mockApi) {
+					api.dnsZones[500] = &enclaveDns.DnsZone{Id: 500, Name: "internal"}
+				},
+				config: map[string]any{
+					"name":    "db",
+					"zone_id": 500,
+				},
+				expect: map[string]any{
+					"zone_id": 500,
+					"fqdn":    "db.internal",
+				},
+				check: replacement.expect(false),
+			},
+			{
+				// records can't move zone, so without zone_id it's recreated in the enclave zone
+				config: map[string]any{
+					"name": "db",
+				},
+				expect: map[string]any{
+					"zone_id": 1,
+					"fqdn":    "db.enclave",
+				},
+				check: replacement.expect(true),
+			},
+			{
+				// the same zone as the default
+				config: map[string]any{
+					"name":    "db",
+					"zone_id": 1,
+				},
+				check: replacement.expect(false),
+			},
+			{
+				config: map[string]any{
+					"name":    "db",
+					"zone_id": 500,
+				},
+				expect: map[string]any{
+					"fqdn": "db.internal",
+				},
+				check: replacement.expect(true),
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+	})
+}
+
+func TestDnsRecordResourceUpgradeState(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	upgraded, err := p.upgradeState("enclave_dns_record", 0, `{
+		"id": 7,
+		"zone_id": null,
+		"name": "db",
+		"tags": ["sql", "database"],
+		"systems": [],
+		"notes": null,
+		"fqdn": "db.enclave"
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"id":              int64(7),
+		"zone_id":         nil,
+		"name":            "db",
+		"tags":            []any{"database", "sql"},
+		"systems":         []any{},
+		"notes":           nil,
+		"fqdn":            "db.enclave",
+		"organisation_id": nil,
+		"timeouts":        nil,
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("expected %#v, got %#v", expected, upgraded)
+	}
+}
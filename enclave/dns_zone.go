@@ -3,19 +3,20 @@ package enclave
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	enclaveDns "github.com/enclave-networks/go-enclaveapi/data/dns"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 type dnsZoneResourceType struct{}
 
 func (d dnsZoneResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 0,
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
 				Type:     types.Int64Type,
@@ -29,6 +30,10 @@ func (d dnsZoneResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Di
 				Type:     types.StringType,
 				Optional: true,
 			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -63,18 +68,27 @@ func (d dnsZone) Create(ctx context.Context, req tfsdk.CreateResourceRequest, re
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
 	dnsZoneCreate := enclaveDns.DnsZoneCreate{
 		Name:  plan.Name.Value,
 		Notes: plan.Notes.Value,
 	}
 
 	// create request
-	dnsZone, err := d.provider.client.Dns.CreateZone(dnsZoneCreate)
+	var dnsZone enclaveDns.DnsZone
+	err := org.api.do(http.MethodPost, "/dns/zones", nil, dnsZoneCreate, &dnsZone)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating DnsZone in enclave",
-			err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating DnsZone in enclave", "Could not create DNS zone", err)
 		return
 	}
 
@@ -97,15 +111,22 @@ func (d dnsZone) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, re
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	dnsZoneId := enclaveDns.DnsZoneId(state.Id.Value)
 
 	//call api to delete
-	_, err := d.provider.client.Dns.DeleteZone(dnsZoneId)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting Dns Zone",
-			"Could not read Id "+fmt.Sprint(dnsZoneId)+": "+err.Error(),
-		)
+	err := org.api.do(http.MethodDelete, fmt.Sprintf("/dns/zones/%d", dnsZoneId), nil, nil, nil)
+	// already deleted outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error deleting Dns Zone", "Could not delete Id "+fmt.Sprint(dnsZoneId), err)
 		return
 	}
 
@@ -122,18 +143,32 @@ func (d dnsZone) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
 	dnsZoneId := enclaveDns.DnsZoneId(state.Id.Value)
 
-	dnsZone, err := d.provider.client.Dns.GetZone(dnsZoneId)
+	var dnsZone enclaveDns.DnsZone
+	err := org.api.get(fmt.Sprintf("/dns/zones/%d", dnsZoneId), nil, &dnsZone)
+	if isNotFound(err) {
+		// the dns zone has been deleted outside of terraform, remove it so it gets recreated
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error reading Dns Zone",
-			"Could not read Id "+fmt.Sprint(dnsZoneId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading Dns Zone", "Could not read Id "+fmt.Sprint(dnsZoneId), err)
 		return
 	}
 
-	setDnsZoneStateId(dnsZone, &state)
+	setDnsZoneState(dnsZone, &state)
 
 	diags = resp.State.Set(ctx, state)
 	resp.Diagnostics.Append(diags...)
@@ -157,18 +192,26 @@ func (d dnsZone) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, re
 	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := d.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
 	dnsZoneId := enclaveDns.DnsZoneId(state.Id.Value)
 
-	updateDnsZone, err := d.provider.client.Dns.UpdateZone(dnsZoneId, enclaveDns.DnsZonePatch{
-		Notes: plan.Notes.Value,
-		Name:  plan.Name.Value,
-	})
+	var updateDnsZone enclaveDns.DnsZone
+	err := org.api.do(http.MethodPatch, fmt.Sprintf("/dns/zones/%d", dnsZoneId), nil, dnsZonePatch{
+		Notes: &plan.Notes.Value,
+		Name:  &plan.Name.Value,
+	}, &updateDnsZone)
 
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error updating Dns Zone",
-			"Could not read Id "+fmt.Sprint(dnsZoneId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating Dns Zone", "Could not update Id "+fmt.Sprint(dnsZoneId), err)
 		return
 	}
 
@@ -181,11 +224,29 @@ func (d dnsZone) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, re
 	}
 }
 
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (d dnsZone) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, d.provider, req, resp)
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (d dnsZone) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	// still on the first version of its schema, so there's nothing to upgrade from yet
+	return stateUpgraders()
+}
+
 // ImportState implements tfsdk.Resource
 func (dnsZone) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
-	tfsdk.ResourceImportStatePassthroughID(ctx, tftypes.NewAttributePath().WithAttributeName("id"), req, resp)
+	importStateInt64Id(ctx, req, resp)
 }
 
 func setDnsZoneStateId(dnsZone enclaveDns.DnsZone, state *DnsZoneState) {
 	state.Id = types.Int64{Value: int64(dnsZone.Id)}
 }
+
+// Map the full api zone back into state so out-of-band changes are detected
+func setDnsZoneState(dnsZone enclaveDns.DnsZone, state *DnsZoneState) {
+	setDnsZoneStateId(dnsZone, state)
+	state.Name = types.String{Value: dnsZone.Name}
+	state.Notes = stringValueOrNull(dnsZone.Notes)
+}
@@ -0,0 +1,76 @@
+package enclave
+
+import (
+	"fmt"
+	"testing"
+
+	enclaveDns "github.com/enclave-networks/go-enclaveapi/data/dns"
+)
+
+func TestDnsZoneResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_dns_zone",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"name": "internal",
+				},
+				expect: map[string]any{
+					"name":  "internal",
+					"notes": nil,
+				},
+			},
+			{
+				config: map[string]any{
+					"name":  "internal.example",
+					"notes": "internal services",
+				},
+				expect: map[string]any{
+					"name":  "internal.example",
+					"notes": "internal services",
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					for id := range api.dnsZones {
+						if id != defaultDnsZoneId {
+							delete(api.dnsZones, id)
+						}
+					}
+				},
+				config: map[string]any{
+					"name":  "internal.example",
+					"notes": "internal services",
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if len(api.dnsZones) != 2 {
+						t.Errorf("expected the zone to be recreated, got %d zones", len(api.dnsZones))
+					}
+				},
+			},
+			{
+				// removing the notes clears them
+				config: map[string]any{
+					"name": "internal.example",
+				},
+				expect: map[string]any{
+					"notes": nil,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if zone := api.dnsZones[enclaveDns.DnsZoneId(state["id"].(int64))]; zone.Notes != "" {
+						t.Errorf("expected the notes to be cleared, got %q", zone.Notes)
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+		checkDestroy: func(api *mockApi) error {
+			if len(api.dnsZones) != 1 {
+				return fmt.Errorf("%d zones still exist", len(api.dnsZones)-1)
+			}
+			return nil
+		},
+	})
+}
@@ -3,6 +3,7 @@ package enclave
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
@@ -17,6 +18,7 @@ type enrolmentKeyResourceType struct{}
 
 func (e enrolmentKeyResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 1,
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
 				Type:     types.Int64Type,
@@ -30,25 +32,50 @@ func (e enrolmentKeyResourceType) GetSchema(_ context.Context) (tfsdk.Schema, di
 			"type": {
 				Type:     types.StringType,
 				Optional: true,
+				Computed: true,
+				Validators: []tfsdk.AttributeValidator{
+					oneOfValidator{values: []string{"general", "ephemeral"}},
+				},
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					defaultValue(types.String{Value: "general"}),
+					// there's no changing the type of an existing key
+					requiresReplace(),
+				},
 			},
 			"approval_mode": {
 				Type:     types.StringType,
 				Optional: true,
+				Computed: true,
+				Validators: []tfsdk.AttributeValidator{
+					oneOfValidator{values: []string{"automatic", "manual"}},
+				},
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					defaultValue(types.String{Value: "manual"}),
+				},
 			},
 			"description": {
 				Type:     types.StringType,
 				Required: true,
 			},
+			// the api has no way to clear the retention, so it keeps its value when it's removed from config
 			"disconnected_retention_minutes": {
 				Type:     types.Int64Type,
 				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
 			},
 			"tags": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.StringType,
 				},
 				Optional: true,
 			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -64,6 +91,33 @@ type enrolmentKey struct {
 	provider provider
 }
 
+// ValidateConfig implements tfsdk.ResourceWithValidateConfig
+func (e enrolmentKey) ValidateConfig(ctx context.Context, req tfsdk.ValidateResourceConfigRequest, resp *tfsdk.ValidateResourceConfigResponse) {
+	// only read the attributes being checked, the rest of the config can be unknown in ways the state
+	// struct can't hold, like tags that come from another resource
+	var keyType types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("type"), &keyType)...)
+
+	var retention types.Int64
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("disconnected_retention_minutes"), &retention)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if retention.Null || keyType.Unknown {
+		return
+	}
+
+	// keys are general purpose unless they're set to be ephemeral
+	if keyType.Null || !strings.EqualFold(keyType.Value, "ephemeral") {
+		resp.Diagnostics.AddAttributeError(
+			tftypes.NewAttributePath().WithAttributeName("disconnected_retention_minutes"),
+			"Invalid disconnected_retention_minutes",
+			"disconnected_retention_minutes can only be set for enrolment keys with the type \"ephemeral\", as only ephemeral systems are removed when they disconnect",
+		)
+	}
+}
+
 // Create a new resource
 func (e enrolmentKey) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
 	if !e.provider.configured {
@@ -85,38 +139,34 @@ func (e enrolmentKey) Create(ctx context.Context, req tfsdk.CreateResourceReques
 		return
 	}
 
-	var enrolmentKeyType enclaveEnrolmentKey.EnrolmentKeyType
-	if !plan.Type.Null {
-		val, err := getType(plan.Type.Value)
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Error converting string to enum for enrolmentKeyType",
-				err.Error(),
-			)
-			return
-		}
-
-		enrolmentKeyType = val
-	} else {
-		val := enclaveEnrolmentKey.GeneralPurpose
-		enrolmentKeyType = val
-	}
-
-	var approvalModeType enclaveEnrolmentKey.EnrolmentKeyApprovalMode
-	if !plan.ApprovalMode.Null {
-		val, err := getApprovalMode(plan.ApprovalMode.Value)
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Error converting string to enum for approvalModeType",
-				err.Error(),
-			)
-			return
-		}
-
-		approvalModeType = val
-	} else {
-		val := enclaveEnrolmentKey.Manual
-		approvalModeType = val
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := e.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
+	// both are always planned, either from config or their defaults, and have been validated
+	enrolmentKeyType, err := getType(plan.Type.Value)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error converting string to enum for enrolmentKeyType",
+			err.Error(),
+		)
+		return
+	}
+
+	approvalModeType, err := getApprovalMode(plan.ApprovalMode.Value)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error converting string to enum for approvalModeType",
+			err.Error(),
+		)
+		return
 	}
 
 	enrolmentKeyCreate := enclaveEnrolmentKey.EnrolmentKeyCreate{
@@ -128,12 +178,10 @@ func (e enrolmentKey) Create(ctx context.Context, req tfsdk.CreateResourceReques
 	}
 
 	// create request
-	enrolmentKeyResponse, err := e.provider.client.EnrolmentKeys.Create(enrolmentKeyCreate)
+	var enrolmentKeyResponse enclaveEnrolmentKey.EnrolmentKey
+	err = org.api.do(http.MethodPost, "/enrolment-keys", nil, enrolmentKeyCreate, &enrolmentKeyResponse)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating EnrolmentKey in enclave",
-			err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating EnrolmentKey in enclave", "Could not create enrolment key", err)
 		return
 	}
 
@@ -155,18 +203,32 @@ func (e enrolmentKey) Read(ctx context.Context, req tfsdk.ReadResourceRequest, r
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := e.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
 	enrolmentKeyId := enclaveEnrolmentKey.EnrolmentKeyId(state.Id.Value)
 
-	currentEnrolmentKey, err := e.provider.client.EnrolmentKeys.Get(enrolmentKeyId)
+	var currentEnrolmentKey enclaveEnrolmentKey.EnrolmentKey
+	err := org.api.get(fmt.Sprintf("/enrolment-keys/%d", enrolmentKeyId), nil, &currentEnrolmentKey)
+	if isNotFound(err) {
+		// the enrolment key has been deleted outside of terraform, remove it so it gets recreated
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error reading enrolment Key",
-			"Could not read Id "+fmt.Sprint(enrolmentKeyId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading enrolment Key", "Could not read Id "+fmt.Sprint(enrolmentKeyId), err)
 		return
 	}
 
-	setEnrolmentKeyStateValues(currentEnrolmentKey, &state)
+	setEnrolmentKeyState(currentEnrolmentKey, &state)
 
 	diags = resp.State.Set(ctx, state)
 	resp.Diagnostics.Append(diags...)
@@ -193,38 +255,45 @@ func (e enrolmentKey) Update(ctx context.Context, req tfsdk.UpdateResourceReques
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := e.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
 	enrolmentKeyId := enclaveEnrolmentKey.EnrolmentKeyId(state.Id.Value)
 
-	var approvalModeType enclaveEnrolmentKey.EnrolmentKeyApprovalMode
-	if !plan.ApprovalMode.Null {
-		val, err := getApprovalMode(plan.ApprovalMode.Value)
-		if err != nil {
-			resp.Diagnostics.AddError(
-				"Error converting string to enum for approvalModeType",
-				err.Error(),
-			)
-			return
-		}
+	approvalModeType, err := getApprovalMode(plan.ApprovalMode.Value)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error converting string to enum for approvalModeType",
+			err.Error(),
+		)
+		return
+	}
+
+	patch := enrolmentKeyPatch{
+		Description:  &plan.Description.Value,
+		ApprovalMode: &approvalModeType,
+		Tags:         patchList(plan.Tags),
+	}
 
-		approvalModeType = val
-	} else {
-		val := enclaveEnrolmentKey.Manual
-		approvalModeType = val
+	// the retention is only unknown on update when it's set from another resource
+	if !plan.DisconnectedRetentionMinutes.Null && !plan.DisconnectedRetentionMinutes.Unknown {
+		retention := int(plan.DisconnectedRetentionMinutes.Value)
+		patch.DisconnectedRetentionMinutes = &retention
 	}
 
 	// call api to update
-	updateEnrolmentKey, err := e.provider.client.EnrolmentKeys.Update(enrolmentKeyId, enclaveEnrolmentKey.EnrolmentKeyPatch{
-		Description:                  plan.Description.Value,
-		ApprovalMode:                 approvalModeType,
-		Tags:                         plan.Tags,
-		DisconnectedRetentionMinutes: int(plan.DisconnectedRetentionMinutes.Value),
-	})
+	var updateEnrolmentKey enclaveEnrolmentKey.EnrolmentKey
+	err = org.api.do(http.MethodPatch, fmt.Sprintf("/enrolment-keys/%d", enrolmentKeyId), nil, patch, &updateEnrolmentKey)
 
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error updating enrolment Key",
-			"Could not read Id "+fmt.Sprint(enrolmentKeyId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating enrolment Key", "Could not update Id "+fmt.Sprint(enrolmentKeyId), err)
 		return
 	}
 
@@ -248,15 +317,22 @@ func (e enrolmentKey) Delete(ctx context.Context, req tfsdk.DeleteResourceReques
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := e.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	enrolmentKeyId := state.Id
 
 	//call api to delete
-	_, err := e.provider.client.EnrolmentKeys.Disable(int(enrolmentKeyId.Value))
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting enrolment Key",
-			"Could not read Id "+fmt.Sprint(enrolmentKeyId.Value)+": "+err.Error(),
-		)
+	err := org.api.do(http.MethodPut, fmt.Sprintf("/enrolment-keys/%d/disable", enrolmentKeyId.Value), nil, nil, nil)
+	// already deleted outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error deleting enrolment Key", "Could not delete Id "+fmt.Sprint(enrolmentKeyId.Value), err)
 		return
 	}
 
@@ -264,10 +340,39 @@ func (e enrolmentKey) Delete(ctx context.Context, req tfsdk.DeleteResourceReques
 	resp.State.RemoveResource(ctx)
 }
 
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (e enrolmentKey) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, e.provider, req, resp)
+}
+
 // Import resource
 func (e enrolmentKey) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
-	// Save the import identifier in the id attribute
-	tfsdk.ResourceImportStatePassthroughID(ctx, tftypes.NewAttributePath().WithAttributeName("id"), req, resp)
+	importStateInt64Id(ctx, req, resp)
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (e enrolmentKey) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		// version 0 had a list of tags, which is now a set so their order doesn't matter
+		stateUpgrade{
+			priorSchema: tfsdk.Schema{
+				Attributes: map[string]tfsdk.Attribute{
+					"id":                             {Type: types.Int64Type, Computed: true},
+					"key":                            {Type: types.StringType, Computed: true, Sensitive: true},
+					"type":                           {Type: types.StringType, Optional: true, Computed: true},
+					"approval_mode":                  {Type: types.StringType, Optional: true, Computed: true},
+					"description":                    {Type: types.StringType, Required: true},
+					"disconnected_retention_minutes": {Type: types.Int64Type, Optional: true},
+					"tags":                           {Type: types.ListType{ElemType: types.StringType}, Optional: true},
+					"organisation_id":                {Type: types.StringType, Optional: true, Computed: true},
+				},
+				Blocks: map[string]tfsdk.Block{
+					"timeouts": timeoutsBlock(),
+				},
+			},
+			upgrade: listsToSets("tags"),
+		},
+	)
 }
 
 // Get EnrolmentKeyType from string
@@ -293,7 +398,36 @@ func getApprovalMode(approvalModeString string) (enclaveEnrolmentKey.EnrolmentKe
 	return "", fmt.Errorf("error when converting %s to EnrolmentKeyApprovalMode", approvalModeString)
 }
 
+// Get the string used in config for an EnrolmentKeyType
+func fromType(enrolmentKeyType enclaveEnrolmentKey.EnrolmentKeyType) string {
+	switch enrolmentKeyType {
+	case enclaveEnrolmentKey.GeneralPurpose:
+		return "general"
+	case enclaveEnrolmentKey.Ephemeral:
+		return "ephemeral"
+	}
+
+	return strings.ToLower(string(enrolmentKeyType))
+}
+
 func setEnrolmentKeyStateValues(enrolmentKey enclaveEnrolmentKey.EnrolmentKey, state *EnrolmentKeyState) {
 	state.Id = types.Int64{Value: int64(enrolmentKey.Id)}
 	state.Key = types.String{Value: enrolmentKey.Key}
+	state.DisconnectedRetentionMinutes = int64ValueOrNull(int64(enrolmentKey.DisconnectedRetentionMinutes))
+}
+
+// Map the full api enrolment key back into state so out-of-band changes are detected
+func setEnrolmentKeyState(enrolmentKey enclaveEnrolmentKey.EnrolmentKey, state *EnrolmentKeyState) {
+	setEnrolmentKeyStateValues(enrolmentKey, state)
+	state.Description = types.String{Value: enrolmentKey.Description}
+	state.Tags = fromTagReferences(enrolmentKey.Tags, state.Tags)
+
+	// keep the configured casing when the value hasn't changed
+	if currentType, err := getType(state.Type.Value); state.Type.Null || err != nil || currentType != enrolmentKey.Type {
+		state.Type = types.String{Value: fromType(enrolmentKey.Type)}
+	}
+
+	if currentApprovalMode, err := getApprovalMode(state.ApprovalMode.Value); state.ApprovalMode.Null || err != nil || currentApprovalMode != enrolmentKey.ApprovalMode {
+		state.ApprovalMode = types.String{Value: strings.ToLower(string(enrolmentKey.ApprovalMode))}
+	}
 }
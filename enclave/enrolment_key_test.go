@@ -0,0 +1,295 @@
+package enclave
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestEnrolmentKeyResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_enrolment_key",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description": "servers",
+					"tags":        []string{"server"},
+				},
+				expect: map[string]any{
+					"description":   "servers",
+					"type":          "general",
+					"approval_mode": "manual",
+					"tags":          []string{"server"},
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					enrolmentKey := api.enrolmentKeys[enclaveEnrolmentKey.EnrolmentKeyId(state["id"].(int64))]
+					if enrolmentKey.Type != enclaveEnrolmentKey.GeneralPurpose || enrolmentKey.ApprovalMode != enclaveEnrolmentKey.Manual {
+						t.Errorf("expected a manual general purpose key, got %s %s", enrolmentKey.ApprovalMode, enrolmentKey.Type)
+					}
+					if state["key"] != enrolmentKey.Key {
+						t.Errorf("expected key %s, got %s", enrolmentKey.Key, state["key"])
+					}
+				},
+			},
+			{
+				config: map[string]any{
+					"description":   "web servers",
+					"approval_mode": "Automatic",
+					"tags":          []string{"server", "web"},
+				},
+				expect: map[string]any{
+					"description":   "web servers",
+					"approval_mode": "Automatic",
+					"tags":          []string{"server", "web"},
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					for _, enrolmentKey := range api.enrolmentKeys {
+						enrolmentKey.ApprovalMode = enclaveEnrolmentKey.Manual
+					}
+				},
+				config: map[string]any{
+					"description":   "web servers",
+					"approval_mode": "automatic",
+					"tags":          []string{"server", "web"},
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					for _, enrolmentKey := range api.enrolmentKeys {
+						if enrolmentKey.ApprovalMode != enclaveEnrolmentKey.Automatic {
+							t.Errorf("expected the approval mode to be put back, got %s", enrolmentKey.ApprovalMode)
+						}
+					}
+				},
+			},
+			{
+				// removing the approval mode goes back to the default
+				config: map[string]any{
+					"description": "web servers",
+					"tags":        []string{"server", "web"},
+				},
+				expect: map[string]any{
+					"approval_mode": "manual",
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					for _, enrolmentKey := range api.enrolmentKeys {
+						if enrolmentKey.ApprovalMode != enclaveEnrolmentKey.Manual {
+							t.Errorf("expected the approval mode to be manual, got %s", enrolmentKey.ApprovalMode)
+						}
+					}
+				},
+			},
+			{
+				// removing the tags clears them
+				config: map[string]any{
+					"description": "web servers",
+				},
+				expect: map[string]any{
+					"tags": nil,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					for _, enrolmentKey := range api.enrolmentKeys {
+						if len(enrolmentKey.Tags) != 0 {
+							t.Errorf("expected the tags to be cleared, got %v", enrolmentKey.Tags)
+						}
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+		checkDestroy: func(api *mockApi) error {
+			for _, enrolmentKey := range api.enrolmentKeys {
+				if enrolmentKey.IsEnabled {
+					return fmt.Errorf("enrolment key %d is still enabled", enrolmentKey.Id)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func TestEphemeralEnrolmentKeyResource(t *testing.T) {
+	var replacement replacementCheck
+
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_enrolment_key",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description":                    "build agents",
+					"type":                           "ephemeral",
+					"approval_mode":                  "automatic",
+					"disconnected_retention_minutes": 15,
+				},
+				expect: map[string]any{
+					"type":                           "ephemeral",
+					"disconnected_retention_minutes": 15,
+				},
+				check: replacement.expect(false),
+			},
+			{
+				config: map[string]any{
+					"description":                    "build agents",
+					"type":                           "Ephemeral",
+					"approval_mode":                  "automatic",
+					"disconnected_retention_minutes": 15,
+				},
+				expect: map[string]any{
+					"type": "Ephemeral",
+				},
+				check: replacement.expect(false),
+			},
+			{
+				// the api can't clear the retention, so removing it keeps the value the key has
+				config: map[string]any{
+					"description":   "build agents",
+					"type":          "Ephemeral",
+					"approval_mode": "automatic",
+				},
+				expect: map[string]any{
+					"disconnected_retention_minutes": 15,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					replacement.expect(false)(t, api, state)
+
+					for _, enrolmentKey := range api.enrolmentKeys {
+						if enrolmentKey.DisconnectedRetentionMinutes != 15 {
+							t.Errorf("expected the retention to be left at 15, got %d", enrolmentKey.DisconnectedRetentionMinutes)
+						}
+					}
+				},
+			},
+			{
+				// the type of a key can't be changed, so going back to the general default makes a new one
+				config: map[string]any{
+					"description":   "build agents",
+					"approval_mode": "automatic",
+				},
+				expect: map[string]any{
+					"type": "general",
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					replacement.expect(true)(t, api, state)
+
+					// keys are disabled rather than deleted
+					for id, enrolmentKey := range api.enrolmentKeys {
+						if enrolmentKey.IsEnabled != (int64(id) == state["id"]) {
+							t.Errorf("expected only the new key to be enabled: %#v", enrolmentKey)
+						}
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+	})
+}
+
+func TestEnrolmentKeyResourceValidation(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_enrolment_key",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description": "servers",
+					"type":        "temporary",
+				},
+				expectError: regexp.MustCompile(`AttributeName\("type"\): Invalid value: "temporary" is not valid, must be one of: general, ephemeral`),
+			},
+			{
+				config: map[string]any{
+					"description":   "servers",
+					"approval_mode": "sometimes",
+				},
+				expectError: regexp.MustCompile(`AttributeName\("approval_mode"\): Invalid value: "sometimes" is not valid, must be one of: automatic, manual`),
+			},
+			{
+				config: map[string]any{
+					"description":                    "servers",
+					"disconnected_retention_minutes": 15,
+				},
+				expectError: regexp.MustCompile(`AttributeName\("disconnected_retention_minutes"\): .*only be set for enrolment keys with the type "ephemeral"`),
+			},
+			{
+				config: map[string]any{
+					"description":                    "servers",
+					"type":                           "General",
+					"disconnected_retention_minutes": 15,
+				},
+				expectError: regexp.MustCompile(`only be set for enrolment keys with the type "ephemeral"`),
+			},
+		},
+	})
+}
+
+func TestEnrolmentKeyResourceValidationUnknown(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	// tags from another resource are unknown until it's applied, which mustn't stop the rest being checked
+	unknownTags := tftypes.NewValue(tftypes.Set{ElementType: tftypes.String}, tftypes.UnknownValue)
+
+	config, err := p.resourceConfig("enclave_enrolment_key", map[string]any{
+		"description":                    "servers",
+		"type":                           "Ephemeral",
+		"disconnected_retention_minutes": 15,
+		"tags":                           unknownTags,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.validate("enclave_enrolment_key", config); err != nil {
+		t.Errorf("expected config with unknown tags to be valid, got %s", err)
+	}
+
+	config, err = p.resourceConfig("enclave_enrolment_key", map[string]any{
+		"description":                    "servers",
+		"disconnected_retention_minutes": 15,
+		"tags":                           unknownTags,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.validate("enclave_enrolment_key", config)
+	if err == nil || !regexp.MustCompile(`only be set for enrolment keys with the type "ephemeral"`).MatchString(err.Error()) {
+		t.Errorf("expected a disconnected_retention_minutes error, got %v", err)
+	}
+}
+
+func TestEnrolmentKeyResourceUpgradeState(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	upgraded, err := p.upgradeState("enclave_enrolment_key", 0, `{
+		"id": 3,
+		"key": "KEY-3",
+		"type": "general",
+		"approval_mode": "manual",
+		"description": "servers",
+		"disconnected_retention_minutes": null,
+		"tags": ["servers", "linux", "servers"]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"id":                             int64(3),
+		"key":                            "KEY-3",
+		"type":                           "general",
+		"approval_mode":                  "manual",
+		"description":                    "servers",
+		"disconnected_retention_minutes": nil,
+		"tags":                           []any{"linux", "servers"},
+		"organisation_id":                nil,
+		"timeouts":                       nil,
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("expected %#v, got %#v", expected, upgraded)
+	}
+}
@@ -0,0 +1,190 @@
+package enclave
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// An unsuccessful response from the enclave api
+type apiError struct {
+	StatusCode int
+	Title      string
+	Detail     string
+
+	// Validation errors for fields of the request, by the path of the field in the request body e.g. Acls[2].Ports
+	Errors map[string][]string
+
+	// How many times the request was sent before giving up
+	Attempts int
+}
+
+func (e *apiError) Error() string {
+	message := fmt.Sprintf("status code does not indicate a successful response %v", e.StatusCode)
+	if e.Attempts > 1 {
+		message += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+
+	if e.Title == "" && e.Detail == "" && len(e.Errors) == 0 {
+		return message
+	}
+
+	message = fmt.Sprintf("%s \n error details \n title: %s \n detail: %s", message, e.Title, e.Detail)
+	for _, field := range sortedFields(e.Errors) {
+		message += fmt.Sprintf(" \n %s: %s", field, strings.Join(e.Errors[field], " "))
+	}
+
+	return message
+}
+
+// A request that failed without a response every time it was retried
+type retriedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *retriedError) Error() string {
+	return fmt.Sprintf("%s (after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *retriedError) Unwrap() error {
+	return e.Err
+}
+
+// Check if an error returned from the api client was caused by the object not existing
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Fields of api requests that have a different name to the attribute they're set from, once in snake case
+var apiFieldAttributes = map[string]string{
+	"acls": "acl",
+	"tag":  "name",
+}
+
+// Add an error from the api to diags, with detail saying what couldn't be done. Validation errors for
+// fields of the request are added against the attribute they came from where it's in schema, so
+// terraform can point at the line of config that caused them.
+func addApiError(diags *diag.Diagnostics, schema tfsdk.Schema, summary string, detail string, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		diags.AddError(summary, detail+": "+err.Error())
+		return
+	}
+
+	var unmatched []string
+	for _, field := range sortedFields(apiErr.Errors) {
+		message := strings.Join(apiErr.Errors[field], " ")
+
+		path := fieldAttributePath(schema, field)
+		if path == nil {
+			unmatched = append(unmatched, fmt.Sprintf("%s: %s", field, message))
+			continue
+		}
+
+		diags.AddAttributeError(path, summary, detail+": "+message)
+	}
+
+	// the field errors say all there is to say if they could all be pointed at config
+	if len(apiErr.Errors) > 0 && len(unmatched) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("%s: the Enclave API responded with %d %s", detail, apiErr.StatusCode, http.StatusText(apiErr.StatusCode))
+	if apiErr.Attempts > 1 {
+		message += fmt.Sprintf(" after %d attempts", apiErr.Attempts)
+	}
+	message += "."
+
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized:
+		message += " Check the token is correct and hasn't expired."
+	case http.StatusForbidden:
+		message += " The token doesn't have permission to do this, check the role its account has in the organisation."
+	case http.StatusNotFound:
+		message += " It may have been deleted outside of terraform."
+	case http.StatusTooManyRequests:
+		message += " The API is limiting how many requests can be made, try again later or raise max_retries."
+	}
+
+	if apiErr.Title != "" && apiErr.Title != http.StatusText(apiErr.StatusCode) {
+		message += "\n\n" + apiErr.Title
+	}
+	if apiErr.Detail != "" {
+		message += "\n\n" + apiErr.Detail
+	}
+	if len(unmatched) > 0 {
+		message += "\n\n" + strings.Join(unmatched, "\n")
+	}
+
+	diags.AddError(summary, message)
+}
+
+// The parts of a field path in an api validation error, e.g. $.Acls[2].Ports
+var fieldPathPattern = regexp.MustCompile(`([^.\[\]$]+)|\[(\d+)\]`)
+
+// Find the attribute in schema an api validation error is about, going up to the closest attribute
+// that exists. Nil if none of it is in schema.
+func fieldAttributePath(schema tfsdk.Schema, field string) *tftypes.AttributePath {
+	var steps []tftypes.AttributePathStep
+	for _, match := range fieldPathPattern.FindAllStringSubmatch(field, -1) {
+		if match[2] != "" {
+			index, _ := strconv.ParseInt(match[2], 10, 64)
+			steps = append(steps, tftypes.ElementKeyInt(index))
+			continue
+		}
+
+		name := toSnakeCase(match[1])
+		if attribute, ok := apiFieldAttributes[name]; ok && len(steps) == 0 {
+			name = attribute
+		}
+		steps = append(steps, tftypes.AttributeName(name))
+	}
+
+	for ; len(steps) > 0; steps = steps[:len(steps)-1] {
+		path := tftypes.NewAttributePathWithSteps(steps)
+
+		// an element of a list of strings has no schema of its own, but is still somewhere in config
+		if _, err := schema.AttributeAtPath(path); err == nil || errors.Is(err, tfsdk.ErrPathInsideAtomicAttribute) {
+			return path
+		}
+	}
+
+	return nil
+}
+
+// Convert a field name from the api, such as SenderTags or senderTags, to the snake case terraform uses
+func toSnakeCase(name string) string {
+	var snake strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				snake.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		snake.WriteRune(r)
+	}
+
+	return snake.String()
+}
+
+func sortedFields(fieldErrors map[string][]string) []string {
+	fields := make([]string, 0, len(fieldErrors))
+	for field := range fieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
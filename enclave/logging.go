@@ -0,0 +1,167 @@
+package enclave
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// What's logged in place of a secret
+const redacted = "[REDACTED]"
+
+// Headers the api might send the id of a request back in, so it can be matched up with their logs
+var requestIdHeaders = []string{"X-Request-Id", "Request-Id"}
+
+// Body fields that are never logged, such as the key of an enrolment key
+var secretFields = map[string]bool{
+	"key": true,
+}
+
+// Logs each request sent to the api and the response to it, with the token and any secrets redacted.
+// Headers and bodies are only logged at trace level.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	fields := map[string]interface{}{
+		"method": req.Method,
+		"path":   req.URL.Path,
+	}
+	if req.URL.RawQuery != "" {
+		fields["query"] = req.URL.RawQuery
+	}
+
+	tflog.Debug(ctx, "Sending Enclave API request", fields)
+	tflog.Trace(ctx, "Enclave API request details", withFields(fields, map[string]interface{}{
+		"headers": redactHeaders(req.Header),
+		"body":    requestBody(req),
+	}))
+
+	started := time.Now()
+	response, err := t.next.RoundTrip(req)
+	fields = withFields(fields, map[string]interface{}{
+		"duration_ms": time.Since(started).Milliseconds(),
+	})
+
+	if err != nil {
+		tflog.Debug(ctx, "Enclave API request failed", withFields(fields, map[string]interface{}{
+			"error": err.Error(),
+		}))
+		return nil, err
+	}
+
+	fields["status"] = response.StatusCode
+	for _, header := range requestIdHeaders {
+		if requestId := response.Header.Get(header); requestId != "" {
+			fields["request_id"] = requestId
+			break
+		}
+	}
+
+	tflog.Debug(ctx, "Received Enclave API response", fields)
+
+	// the body has to be read to log it, so give the caller a copy to read instead
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	tflog.Trace(ctx, "Enclave API response details", withFields(fields, map[string]interface{}{
+		"headers": redactHeaders(response.Header),
+		"body":    redactBody(body),
+	}))
+
+	return response, nil
+}
+
+// Copy fields with some more added, so each log entry only has the fields meant for it
+func withFields(fields map[string]interface{}, more map[string]interface{}) map[string]interface{} {
+	combined := make(map[string]interface{}, len(fields)+len(more))
+	for name, value := range fields {
+		combined[name] = value
+	}
+	for name, value := range more {
+		combined[name] = value
+	}
+
+	return combined
+}
+
+// Read the body of a request for logging without using it up. Empty if it can't be read again.
+func requestBody(req *http.Request) string {
+	if req.Body == nil || req.GetBody == nil {
+		return ""
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+
+	return redactBody(content)
+}
+
+func redactHeaders(headers http.Header) map[string]interface{} {
+	redactedHeaders := make(map[string]interface{}, len(headers))
+	for name, values := range headers {
+		if strings.EqualFold(name, "Authorization") {
+			redactedHeaders[name] = redacted
+			continue
+		}
+
+		redactedHeaders[name] = strings.Join(values, ", ")
+	}
+
+	return redactedHeaders
+}
+
+// Replace any secret fields in a json body. A body that isn't json has nothing we know to be secret in it.
+func redactBody(body []byte) string {
+	var content interface{}
+	if len(body) == 0 || json.Unmarshal(body, &content) != nil {
+		return string(body)
+	}
+
+	redactedBody, err := json.Marshal(redactFields(content))
+	if err != nil {
+		return redacted
+	}
+
+	return string(redactedBody)
+}
+
+func redactFields(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for name, field := range value {
+			if secretFields[strings.ToLower(name)] {
+				value[name] = redacted
+				continue
+			}
+
+			value[name] = redactFields(field)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactFields(item)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,1032 @@
+package enclave
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	enclaveData "github.com/enclave-networks/go-enclaveapi/data"
+	enclaveDns "github.com/enclave-networks/go-enclaveapi/data/dns"
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	enclaveOrganisation "github.com/enclave-networks/go-enclaveapi/data/organisation"
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
+)
+
+const (
+	mockApiToken = "mock-token"
+	mockOrgId    = "mock-org"
+	mockOrgName  = "Mock Org"
+)
+
+// An in-memory fake of the enclave api, just enough of it for the provider to be tested offline
+type mockApi struct {
+	server *httptest.Server
+
+	mu                sync.Mutex
+	nextId            int
+	orgs              []enclaveData.AccountOrganisation
+	policies          map[enclavePolicy.PolicyId]*enclavePolicy.Policy
+	tags              map[enclaveTag.TagRefId]*enclaveTag.DetailedTag
+	dnsZones          map[enclaveDns.DnsZoneId]*enclaveDns.DnsZone
+	dnsRecords        map[enclaveDns.DnsRecordId]*enclaveDns.DnsRecord
+	trustRequirements map[enclaveTrustRequirement.TrustRequirementId]*enclaveTrustRequirement.TrustRequirement
+	enrolmentKeys     map[enclaveEnrolmentKey.EnrolmentKeyId]*enclaveEnrolmentKey.EnrolmentKey
+	systems           map[enclaveSystem.SystemId]*enrolledSystem
+	unapprovedSystems map[enclaveSystem.SystemId]*enrolledSystem
+
+	// The number of requests made against each organisation
+	orgRequests map[string]int
+
+	// Statuses to fail the next requests with, one for each request
+	failures []int
+
+	// Validation errors to reject the next request that sends a body with, by the field they're for
+	validationErrors map[string][]string
+
+	// How long to wait before handling each request, unless the request is given up on first
+	delay time.Duration
+}
+
+func newMockApi(t *testing.T) *mockApi {
+	api := &mockApi{
+		nextId: 100,
+		orgs: []enclaveData.AccountOrganisation{
+			{OrgId: mockOrgId, OrgName: mockOrgName, Role: enclaveData.Owner},
+		},
+		policies: map[enclavePolicy.PolicyId]*enclavePolicy.Policy{},
+		tags:     map[enclaveTag.TagRefId]*enclaveTag.DetailedTag{},
+		dnsZones: map[enclaveDns.DnsZoneId]*enclaveDns.DnsZone{
+			defaultDnsZoneId: {Id: defaultDnsZoneId, Name: "enclave"},
+		},
+		dnsRecords:        map[enclaveDns.DnsRecordId]*enclaveDns.DnsRecord{},
+		trustRequirements: map[enclaveTrustRequirement.TrustRequirementId]*enclaveTrustRequirement.TrustRequirement{},
+		enrolmentKeys:     map[enclaveEnrolmentKey.EnrolmentKeyId]*enclaveEnrolmentKey.EnrolmentKey{},
+		systems:           map[enclaveSystem.SystemId]*enrolledSystem{},
+		unapprovedSystems: map[enclaveSystem.SystemId]*enrolledSystem{},
+		orgRequests:       map[string]int{},
+	}
+
+	api.server = httptest.NewServer(api)
+	t.Cleanup(api.server.Close)
+
+	return api
+}
+
+func (api *mockApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	delay := api.delay
+	api.mu.Unlock()
+
+	if delay > 0 {
+		// the server only notices the client going away once the body has been read
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	// go-enclaveapi sends the token without a space after Bearer
+	if strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer")) != mockApiToken {
+		writeProblem(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if len(api.failures) > 0 {
+		status := api.failures[0]
+		api.failures = api.failures[1:]
+		writeProblem(w, status, http.StatusText(status))
+		return
+	}
+
+	if len(api.validationErrors) > 0 && r.Body != nil && r.ContentLength != 0 {
+		writeJson(w, http.StatusBadRequest, map[string]any{
+			"title":  "One or more validation errors occurred.",
+			"status": http.StatusBadRequest,
+			"errors": api.validationErrors,
+		})
+		api.validationErrors = nil
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if len(segments) == 2 && segments[0] == "account" && segments[1] == "orgs" {
+		writeJson(w, http.StatusOK, enclaveData.AccountOrganisationTopLevel{Orgs: api.orgs})
+		return
+	}
+
+	if len(segments) < 2 || segments[0] != "org" || !api.hasOrg(segments[1]) {
+		writeProblem(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	api.orgRequests[segments[1]]++
+
+	route := segments[2:]
+	if len(route) == 0 {
+		api.serveOrganisation(w, r, segments[1])
+		return
+	}
+
+	switch route[0] {
+	case "policies":
+		api.servePolicies(w, r, route[1:])
+	case "tags":
+		api.serveTags(w, r, route[1:])
+	case "dns":
+		api.serveDns(w, r, route[1:])
+	case "trust-requirements":
+		api.serveTrustRequirements(w, r, route[1:])
+	case "enrolment-keys":
+		api.serveEnrolmentKeys(w, r, route[1:])
+	case "systems":
+		api.serveSystems(w, r, route[1:])
+	case "unapproved-systems":
+		api.serveUnapprovedSystems(w, r, route[1:])
+	default:
+		writeProblem(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (api *mockApi) serveOrganisation(w http.ResponseWriter, r *http.Request, orgId string) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	for _, org := range api.orgs {
+		if string(org.OrgId) == orgId {
+			writeJson(w, http.StatusOK, enclaveOrganisation.Organisation{
+				Id:                org.OrgId,
+				Created:           time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+				Name:              org.OrgName,
+				Plan:              1,
+				Website:           "https://example.com",
+				MaxSystems:        100,
+				EnrolledSystems:   int64(len(api.systems)),
+				UnapprovedSystems: int64(len(api.unapprovedSystems)),
+			})
+			return
+		}
+	}
+}
+
+func (api *mockApi) hasOrg(orgId string) bool {
+	for _, org := range api.orgs {
+		if string(org.OrgId) == orgId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (api *mockApi) newId() int {
+	api.nextId++
+	return api.nextId
+}
+
+func (api *mockApi) servePolicies(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 && r.Method == http.MethodGet {
+		search := strings.ToLower(r.URL.Query().Get("search"))
+		includeDisabled := r.URL.Query().Get("include_disabled") == "true"
+
+		var policies []enclavePolicy.Policy
+		for _, id := range sortedKeys(api.policies) {
+			policy := api.policies[id]
+			if strings.Contains(strings.ToLower(policy.Description), search) && (includeDisabled || policy.IsEnabled) {
+				policies = append(policies, *policy)
+			}
+		}
+
+		writePage(w, r, policies)
+		return
+	}
+
+	if len(route) == 0 {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var create enclavePolicy.PolicyCreate
+		if !readJson(w, r, &create) {
+			return
+		}
+
+		policy := &enclavePolicy.Policy{
+			Id:                      enclavePolicy.PolicyId(api.newId()),
+			Description:             create.Description,
+			IsEnabled:               create.IsEnabled,
+			Notes:                   create.Notes,
+			SenderTags:              api.tagReferences(create.SenderTags),
+			ReceiverTags:            api.tagReferences(create.ReceiverTags),
+			Acls:                    create.Acls,
+			SenderTrustRequirements: api.usedTrustRequirements(create.TrustRequirements),
+		}
+		api.policies[policy.Id] = policy
+
+		writeJson(w, http.StatusOK, policy)
+		return
+	}
+
+	id, err := strconv.Atoi(route[0])
+	policy, ok := api.policies[enclavePolicy.PolicyId(id)]
+	if err != nil || !ok {
+		writeProblem(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	if len(route) == 2 && r.Method == http.MethodPut {
+		switch route[1] {
+		case "enable":
+			policy.IsEnabled = true
+		case "disable":
+			policy.IsEnabled = false
+		default:
+			writeProblem(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		writeJson(w, http.StatusOK, policy)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, policy)
+	case http.MethodPatch:
+		var patch struct {
+			policyPatch
+			IsEnabled *bool
+		}
+		if !readJson(w, r, &patch) {
+			return
+		}
+
+		// like the real api anything not sent is left alone, while empty values clear a field
+		if patch.Description != nil {
+			policy.Description = *patch.Description
+		}
+		if patch.IsEnabled != nil {
+			policy.IsEnabled = *patch.IsEnabled
+		}
+		if patch.Notes != nil {
+			policy.Notes = *patch.Notes
+		}
+		if patch.SenderTags != nil {
+			policy.SenderTags = api.tagReferences(*patch.SenderTags)
+		}
+		if patch.ReceiverTags != nil {
+			policy.ReceiverTags = api.tagReferences(*patch.ReceiverTags)
+		}
+		if patch.Acls != nil {
+			policy.Acls = *patch.Acls
+		}
+		if patch.TrustRequirements != nil {
+			policy.SenderTrustRequirements = api.usedTrustRequirements(*patch.TrustRequirements)
+		}
+
+		writeJson(w, http.StatusOK, policy)
+	case http.MethodDelete:
+		delete(api.policies, policy.Id)
+		writeJson(w, http.StatusOK, policy)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveTags(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 && r.Method == http.MethodGet {
+		search := strings.ToLower(r.URL.Query().Get("search"))
+
+		var tags []enclaveTag.BasicTag
+		for _, ref := range sortedKeys(api.tags) {
+			tag := api.withTagUsage(*api.tags[ref])
+			if strings.Contains(strings.ToLower(tag.Tag), search) {
+				tags = append(tags, enclaveTag.BasicTag{
+					Tag:            tag.Tag,
+					Ref:            tag.Ref,
+					Colour:         tag.Colour,
+					LastReferenced: tag.LastReferenced,
+					Systems:        tag.Systems,
+					Keys:           tag.Keys,
+					Policies:       tag.Policies,
+					DnsRecords:     tag.DnsRecords,
+				})
+			}
+		}
+
+		writePage(w, r, tags)
+		return
+	}
+
+	if len(route) == 0 {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var create enclaveTag.TagCreate
+		if !readJson(w, r, &create) {
+			return
+		}
+
+		if api.findTag(create.Tag) != nil {
+			writeProblem(w, http.StatusBadRequest, "Tag already exists")
+			return
+		}
+
+		tag := &enclaveTag.DetailedTag{
+			Tag:               create.Tag,
+			Ref:               enclaveTag.TagRefId(fmt.Sprintf("ref%d", api.newId())),
+			Colour:            create.Colour,
+			Notes:             create.Notes,
+			TrustRequirements: api.usedTrustRequirements(create.TrustRequirements),
+		}
+		api.tags[tag.Ref] = tag
+
+		writeJson(w, http.StatusOK, tag)
+		return
+	}
+
+	tag := api.findTag(route[0])
+	if len(route) != 1 || tag == nil {
+		writeProblem(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, api.withTagUsage(*tag))
+	case http.MethodPatch:
+		var patch tagPatch
+		if !readJson(w, r, &patch) {
+			return
+		}
+
+		if patch.Tag != nil {
+			tag.Tag = *patch.Tag
+		}
+		if patch.Colour != nil {
+			tag.Colour = *patch.Colour
+		}
+		if patch.Notes != nil {
+			tag.Notes = *patch.Notes
+		}
+		if patch.TrustRequirements != nil {
+			tag.TrustRequirements = api.usedTrustRequirements(*patch.TrustRequirements)
+		}
+
+		writeJson(w, http.StatusOK, tag)
+	case http.MethodDelete:
+		delete(api.tags, tag.Ref)
+		writeJson(w, http.StatusOK, tag)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveDns(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		writeProblem(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	switch route[0] {
+	case "zones":
+		api.serveDnsZones(w, r, route[1:])
+	case "records":
+		api.serveDnsRecords(w, r, route[1:])
+	default:
+		writeProblem(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (api *mockApi) serveDnsZones(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var create enclaveDns.DnsZoneCreate
+		if !readJson(w, r, &create) {
+			return
+		}
+
+		zone := &enclaveDns.DnsZone{
+			Id:    enclaveDns.DnsZoneId(api.newId()),
+			Name:  create.Name,
+			Notes: create.Notes,
+		}
+		api.dnsZones[zone.Id] = zone
+
+		writeJson(w, http.StatusOK, zone)
+		return
+	}
+
+	id, err := strconv.Atoi(route[0])
+	zone, ok := api.dnsZones[enclaveDns.DnsZoneId(id)]
+	if err != nil || !ok || len(route) != 1 {
+		writeProblem(w, http.StatusNotFound, "Zone not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, zone)
+	case http.MethodPatch:
+		var patch dnsZonePatch
+		if !readJson(w, r, &patch) {
+			return
+		}
+
+		if patch.Name != nil {
+			zone.Name = *patch.Name
+		}
+		if patch.Notes != nil {
+			zone.Notes = *patch.Notes
+		}
+
+		writeJson(w, http.StatusOK, zone)
+	case http.MethodDelete:
+		delete(api.dnsZones, zone.Id)
+		for recordId, record := range api.dnsRecords {
+			if record.ZoneId == zone.Id {
+				delete(api.dnsRecords, recordId)
+			}
+		}
+
+		writeJson(w, http.StatusOK, zone)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveDnsRecords(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var create enclaveDns.DnsRecordCreate
+		if !readJson(w, r, &create) {
+			return
+		}
+
+		zone, ok := api.dnsZones[create.ZoneId]
+		if !ok {
+			writeProblem(w, http.StatusBadRequest, "Zone not found")
+			return
+		}
+
+		record := &enclaveDns.DnsRecord{
+			Id:       enclaveDns.DnsRecordId(api.newId()),
+			Name:     create.Name,
+			Type:     "ENCLAVE",
+			ZoneId:   zone.Id,
+			ZoneName: zone.Name,
+			Fqdn:     create.Name + "." + zone.Name,
+			Tags:     api.tagReferences(create.Tags),
+			Systems:  systemReferences(create.Systems),
+		}
+		api.dnsRecords[record.Id] = record
+
+		writeJson(w, http.StatusOK, record)
+		return
+	}
+
+	id, err := strconv.Atoi(route[0])
+	record, ok := api.dnsRecords[enclaveDns.DnsRecordId(id)]
+	if err != nil || !ok || len(route) != 1 {
+		writeProblem(w, http.StatusNotFound, "Record not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, record)
+	case http.MethodPatch:
+		var patch dnsRecordPatch
+		if !readJson(w, r, &patch) {
+			return
+		}
+
+		if patch.Name != nil {
+			record.Name = *patch.Name
+			record.Fqdn = *patch.Name + "." + record.ZoneName
+		}
+		if patch.Tags != nil {
+			record.Tags = api.tagReferences(*patch.Tags)
+		}
+		if patch.Systems != nil {
+			record.Systems = systemReferences(*patch.Systems)
+		}
+
+		writeJson(w, http.StatusOK, record)
+	case http.MethodDelete:
+		delete(api.dnsRecords, record.Id)
+		writeJson(w, http.StatusOK, record)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveTrustRequirements(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var create enclaveTrustRequirement.TrustRequirementCreate
+		if !readJson(w, r, &create) {
+			return
+		}
+
+		trustRequirement := &enclaveTrustRequirement.TrustRequirement{
+			Id:          enclaveTrustRequirement.TrustRequirementId(api.newId()),
+			Description: create.Description,
+			Type:        create.Type,
+			Notes:       create.Notes,
+			Settings:    create.Settings,
+		}
+		api.trustRequirements[trustRequirement.Id] = trustRequirement
+
+		writeJson(w, http.StatusOK, trustRequirement)
+		return
+	}
+
+	id, err := strconv.Atoi(route[0])
+	trustRequirement, ok := api.trustRequirements[enclaveTrustRequirement.TrustRequirementId(id)]
+	if err != nil || !ok || len(route) != 1 {
+		writeProblem(w, http.StatusNotFound, "Trust requirement not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, trustRequirement)
+	case http.MethodPatch:
+		var patch trustRequirementPatch
+		if !readJson(w, r, &patch) {
+			return
+		}
+
+		if patch.Description != nil {
+			trustRequirement.Description = *patch.Description
+		}
+		if patch.Notes != nil {
+			trustRequirement.Notes = *patch.Notes
+		}
+		if patch.Settings != nil {
+			trustRequirement.Settings = *patch.Settings
+		}
+
+		writeJson(w, http.StatusOK, trustRequirement)
+	case http.MethodDelete:
+		delete(api.trustRequirements, trustRequirement.Id)
+		writeJson(w, http.StatusOK, trustRequirement)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveEnrolmentKeys(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var create enclaveEnrolmentKey.EnrolmentKeyCreate
+		if !readJson(w, r, &create) {
+			return
+		}
+
+		id := api.newId()
+		enrolmentKey := &enclaveEnrolmentKey.EnrolmentKey{
+			Id:                           enclaveEnrolmentKey.EnrolmentKeyId(id),
+			Type:                         create.Type,
+			ApprovalMode:                 create.ApprovalMode,
+			Key:                          fmt.Sprintf("KEY-%d", id),
+			Description:                  create.Description,
+			IsEnabled:                    true,
+			UsesRemaining:                -1,
+			Tags:                         api.tagReferences(create.Tags),
+			DisconnectedRetentionMinutes: create.DisconnectedRetentionMinutes,
+			Notes:                        create.Notes,
+		}
+		api.enrolmentKeys[enrolmentKey.Id] = enrolmentKey
+
+		writeJson(w, http.StatusOK, enrolmentKey)
+		return
+	}
+
+	id, err := strconv.Atoi(route[0])
+	enrolmentKey, ok := api.enrolmentKeys[enclaveEnrolmentKey.EnrolmentKeyId(id)]
+	if err != nil || !ok {
+		writeProblem(w, http.StatusNotFound, "Enrolment key not found")
+		return
+	}
+
+	if len(route) == 2 && r.Method == http.MethodPut {
+		switch route[1] {
+		case "enable":
+			enrolmentKey.IsEnabled = true
+		case "disable":
+			enrolmentKey.IsEnabled = false
+		default:
+			writeProblem(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		writeJson(w, http.StatusOK, enrolmentKey)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, enrolmentKey)
+	case http.MethodPatch:
+		var patch enrolmentKeyPatch
+		if !readJson(w, r, &patch) {
+			return
+		}
+
+		if patch.Description != nil {
+			enrolmentKey.Description = *patch.Description
+		}
+		if patch.ApprovalMode != nil {
+			enrolmentKey.ApprovalMode = *patch.ApprovalMode
+		}
+		if patch.Tags != nil {
+			enrolmentKey.Tags = api.tagReferences(*patch.Tags)
+		}
+		if patch.DisconnectedRetentionMinutes != nil {
+			enrolmentKey.DisconnectedRetentionMinutes = *patch.DisconnectedRetentionMinutes
+		}
+
+		writeJson(w, http.StatusOK, enrolmentKey)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveSystems(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			search := strings.ToLower(r.URL.Query().Get("search"))
+			enrolmentKey := r.URL.Query().Get("enrolment_key")
+			includeDisabled := r.URL.Query().Get("include_disabled") == "true"
+
+			var systems []enrolledSystem
+			for _, id := range sortedKeys(api.systems) {
+				system := api.systems[id]
+				if enrolmentKey != "" && fmt.Sprint(system.EnrolmentKeyId) != enrolmentKey {
+					continue
+				}
+
+				if strings.Contains(strings.ToLower(system.Hostname), search) && (includeDisabled || system.IsEnabled) {
+					systems = append(systems, *system)
+				}
+			}
+
+			writePage(w, r, systems)
+		case http.MethodDelete:
+			var revoke enclaveSystem.EnrolledSystemBulkAction
+			if !readJson(w, r, &revoke) {
+				return
+			}
+
+			revoked := 0
+			for _, id := range revoke.SystemIds {
+				if _, ok := api.systems[id]; ok {
+					delete(api.systems, id)
+					revoked++
+				}
+			}
+
+			writeJson(w, http.StatusOK, enclaveSystem.EnrolledSystemBulkRevokedResult{SystemsRevoked: revoked})
+		default:
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+		return
+	}
+
+	system, ok := api.systems[enclaveSystem.SystemId(route[0])]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "System not found")
+		return
+	}
+
+	if len(route) == 2 && r.Method == http.MethodPut {
+		switch route[1] {
+		case "enable":
+			system.IsEnabled = true
+			system.State = enclaveSystem.Disconnected
+		case "disable":
+			system.IsEnabled = false
+			system.State = enclaveSystem.Disabled
+		default:
+			writeProblem(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		writeJson(w, http.StatusOK, system)
+		return
+	}
+
+	if len(route) != 1 {
+		writeProblem(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, system)
+	case http.MethodPatch:
+		if api.patchSystem(w, r, system) {
+			writeJson(w, http.StatusOK, system)
+		}
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) serveUnapprovedSystems(w http.ResponseWriter, r *http.Request, route []string) {
+	if len(route) == 0 {
+		if r.Method != http.MethodGet {
+			writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+
+		var systems []enrolledSystem
+		for _, id := range sortedKeys(api.unapprovedSystems) {
+			systems = append(systems, *api.unapprovedSystems[id])
+		}
+
+		writePage(w, r, systems)
+		return
+	}
+
+	system, ok := api.unapprovedSystems[enclaveSystem.SystemId(route[0])]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "System not found")
+		return
+	}
+
+	if len(route) == 2 && route[1] == "approve" && r.Method == http.MethodPut {
+		delete(api.unapprovedSystems, system.SystemId)
+
+		system.IsEnabled = true
+		system.State = enclaveSystem.Disconnected
+		system.VirtualAddress = fmt.Sprintf("100.64.0.%d", len(api.systems)+1)
+		api.systems[system.SystemId] = system
+
+		writeJson(w, http.StatusOK, system)
+		return
+	}
+
+	if len(route) != 1 {
+		writeProblem(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJson(w, http.StatusOK, system)
+	case http.MethodPatch:
+		if api.patchSystem(w, r, system) {
+			writeJson(w, http.StatusOK, system)
+		}
+	case http.MethodDelete:
+		delete(api.unapprovedSystems, system.SystemId)
+		writeJson(w, http.StatusOK, system)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (api *mockApi) patchSystem(w http.ResponseWriter, r *http.Request, system *enrolledSystem) bool {
+	var patch enrolledSystemPatch
+	if !readJson(w, r, &patch) {
+		return false
+	}
+
+	if patch.Description != nil {
+		system.Description = *patch.Description
+	}
+	if patch.Notes != nil {
+		system.Notes = *patch.Notes
+	}
+	if patch.Tags != nil {
+		system.Tags = api.tagReferences(*patch.Tags)
+	}
+
+	return true
+}
+
+// Find a tag by name or ref
+func (api *mockApi) findTag(nameOrRef string) *enclaveTag.DetailedTag {
+	if tag, ok := api.tags[enclaveTag.TagRefId(nameOrRef)]; ok {
+		return tag
+	}
+
+	for _, tag := range api.tags {
+		if tag.Tag == nameOrRef {
+			return tag
+		}
+	}
+
+	return nil
+}
+
+// Count what references a tag, the api works these out when a tag is read
+func (api *mockApi) withTagUsage(tag enclaveTag.DetailedTag) enclaveTag.DetailedTag {
+	tag.Policies = 0
+	for _, policy := range api.policies {
+		if hasTagReference(policy.SenderTags, tag.Ref) || hasTagReference(policy.ReceiverTags, tag.Ref) {
+			tag.Policies++
+		}
+	}
+
+	tag.Keys = 0
+	for _, enrolmentKey := range api.enrolmentKeys {
+		if hasTagReference(enrolmentKey.Tags, tag.Ref) {
+			tag.Keys++
+		}
+	}
+
+	tag.Systems = 0
+	for _, system := range api.systems {
+		if hasTagReference(system.Tags, tag.Ref) {
+			tag.Systems++
+		}
+	}
+
+	tag.DnsRecords = 0
+	for _, record := range api.dnsRecords {
+		if hasTagReference(record.Tags, tag.Ref) {
+			tag.DnsRecords++
+		}
+	}
+
+	return tag
+}
+
+func hasTagReference(refs []enclaveTag.TagReference, ref enclaveTag.TagRefId) bool {
+	for _, tagRef := range refs {
+		if tagRef.Ref == ref {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Tags referenced by name are created if they don't exist, the same as the real api
+func (api *mockApi) tagReferences(names []string) []enclaveTag.TagReference {
+	if len(names) == 0 {
+		return nil
+	}
+
+	refs := make([]enclaveTag.TagReference, len(names))
+	for i, name := range names {
+		tag := api.findTag(name)
+		if tag == nil {
+			tag = &enclaveTag.DetailedTag{
+				Tag: name,
+				Ref: enclaveTag.TagRefId(fmt.Sprintf("ref%d", api.newId())),
+			}
+			api.tags[tag.Ref] = tag
+		}
+
+		refs[i] = enclaveTag.TagReference{Tag: tag.Tag, Ref: tag.Ref, Colour: tag.Colour}
+	}
+
+	return refs
+}
+
+func (api *mockApi) usedTrustRequirements(ids []enclaveTrustRequirement.TrustRequirementId) []enclaveTrustRequirement.UsedTrustRequirement {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	used := make([]enclaveTrustRequirement.UsedTrustRequirement, len(ids))
+	for i, id := range ids {
+		used[i] = enclaveTrustRequirement.UsedTrustRequirement{Id: id}
+		if trustRequirement, ok := api.trustRequirements[id]; ok {
+			used[i].Description = trustRequirement.Description
+			used[i].Type = trustRequirement.Type
+		}
+	}
+
+	return used
+}
+
+func systemReferences(ids []string) []enclaveSystem.SystemReference {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	refs := make([]enclaveSystem.SystemReference, len(ids))
+	for i, id := range ids {
+		refs[i] = enclaveSystem.SystemReference{Id: enclaveSystem.SystemId(id)}
+	}
+
+	return refs
+}
+
+// Write a page of items the same way the api paginates them, pages start at 0
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 50
+	}
+
+	lastPage := 0
+	if len(items) > 0 {
+		lastPage = (len(items) - 1) / perPage
+	}
+
+	start := page * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+
+	nextPage := page
+	if page < lastPage {
+		nextPage = page + 1
+	}
+
+	writeJson(w, http.StatusOK, enclaveData.PaginatedResponse[T]{
+		Metadata: enclaveData.PaginationMetaData{
+			Total:     len(items),
+			FirstPage: 0,
+			LastPage:  lastPage,
+			NextPage:  nextPage,
+		},
+		Items: items[start:end],
+	})
+}
+
+// Map keys in order so lists from the mock api are stable
+func sortedKeys[K ~int | ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
+
+func readJson(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	return true
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, statusCode int, title string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(enclaveData.HttpErrorResponse{Title: title})
+}
@@ -5,13 +5,15 @@ import (
 )
 
 type EnrolmentKeyState struct {
-	Id                           types.Int64  `tfsdk:"id"`
-	Key                          types.String `tfsdk:"key"`
-	Type                         types.String `tfsdk:"type"`
-	ApprovalMode                 types.String `tfsdk:"approval_mode"`
-	Description                  types.String `tfsdk:"description"`
-	DisconnectedRetentionMinutes types.Int64  `tfsdk:"disconnected_retention_minutes"`
-	Tags                         []string     `tfsdk:"tags"`
+	Id                           types.Int64     `tfsdk:"id"`
+	Key                          types.String    `tfsdk:"key"`
+	Type                         types.String    `tfsdk:"type"`
+	ApprovalMode                 types.String    `tfsdk:"approval_mode"`
+	Description                  types.String    `tfsdk:"description"`
+	DisconnectedRetentionMinutes types.Int64     `tfsdk:"disconnected_retention_minutes"`
+	Tags                         []string        `tfsdk:"tags"`
+	OrganisationId               types.String    `tfsdk:"organisation_id"`
+	Timeouts                     []TimeoutsState `tfsdk:"timeouts"`
 }
 
 type PolicyState struct {
@@ -23,6 +25,88 @@ type PolicyState struct {
 	ReceiverTags      []string         `tfsdk:"receiver_tags"`
 	Acl               []PolicyAclState `tfsdk:"acl"`
 	TrustRequirements []types.Int64    `tfsdk:"trust_requirements"`
+	OrganisationId    types.String     `tfsdk:"organisation_id"`
+	Timeouts          []TimeoutsState  `tfsdk:"timeouts"`
+}
+
+// A policy as read by the policy data sources, which have no timeouts
+type PolicyDataSourceState struct {
+	Id                types.Int64      `tfsdk:"id"`
+	Description       types.String     `tfsdk:"description"`
+	Notes             types.String     `tfsdk:"notes"`
+	IsEnabled         types.Bool       `tfsdk:"is_enabled"`
+	SenderTags        []string         `tfsdk:"sender_tags"`
+	ReceiverTags      []string         `tfsdk:"receiver_tags"`
+	Acl               []PolicyAclState `tfsdk:"acl"`
+	TrustRequirements []types.Int64    `tfsdk:"trust_requirements"`
+	OrganisationId    types.String     `tfsdk:"organisation_id"`
+}
+
+type PoliciesState struct {
+	SenderTag           types.String            `tfsdk:"sender_tag"`
+	ReceiverTag         types.String            `tfsdk:"receiver_tag"`
+	IsEnabled           types.Bool              `tfsdk:"is_enabled"`
+	DescriptionContains types.String            `tfsdk:"description_contains"`
+	Policies            []PolicyDataSourceState `tfsdk:"policies"`
+}
+
+type TagDataSourceState struct {
+	Ref               types.String  `tfsdk:"ref"`
+	Name              types.String  `tfsdk:"name"`
+	Colour            types.String  `tfsdk:"colour"`
+	Notes             types.String  `tfsdk:"notes"`
+	TrustRequirements []types.Int64 `tfsdk:"trust_requirements"`
+	SystemCount       types.Int64   `tfsdk:"system_count"`
+	EnrolmentKeyCount types.Int64   `tfsdk:"enrolment_key_count"`
+	PolicyCount       types.Int64   `tfsdk:"policy_count"`
+	DnsRecordCount    types.Int64   `tfsdk:"dns_record_count"`
+}
+
+type TagsState struct {
+	Search types.String         `tfsdk:"search"`
+	Tags   []TagDataSourceState `tfsdk:"tags"`
+}
+
+type SystemState struct {
+	Id             types.String    `tfsdk:"id"`
+	Hostname       types.String    `tfsdk:"hostname"`
+	EnrolmentKeyId types.Int64     `tfsdk:"enrolment_key_id"`
+	Description    types.String    `tfsdk:"description"`
+	Notes          types.String    `tfsdk:"notes"`
+	Tags           types.Set       `tfsdk:"tags"`
+	IsEnabled      types.Bool      `tfsdk:"is_enabled"`
+	IsApproved     types.Bool      `tfsdk:"is_approved"`
+	VirtualAddress types.String    `tfsdk:"virtual_address"`
+	OrganisationId types.String    `tfsdk:"organisation_id"`
+	Timeouts       []TimeoutsState `tfsdk:"timeouts"`
+}
+
+type SystemApprovalState struct {
+	Id             types.String    `tfsdk:"id"`
+	Hostname       types.String    `tfsdk:"hostname"`
+	EnrolmentKeyId types.Int64     `tfsdk:"enrolment_key_id"`
+	Timeout        types.String    `tfsdk:"timeout"`
+	OrganisationId types.String    `tfsdk:"organisation_id"`
+	Timeouts       []TimeoutsState `tfsdk:"timeouts"`
+}
+
+type SystemDataSourceState struct {
+	Id             types.String `tfsdk:"id"`
+	Hostname       types.String `tfsdk:"hostname"`
+	Description    types.String `tfsdk:"description"`
+	Tags           []string     `tfsdk:"tags"`
+	EnrolmentKeyId types.Int64  `tfsdk:"enrolment_key_id"`
+	VirtualAddress types.String `tfsdk:"virtual_address"`
+	Platform       types.String `tfsdk:"platform"`
+	State          types.String `tfsdk:"state"`
+	IsEnabled      types.Bool   `tfsdk:"is_enabled"`
+}
+
+type SystemsState struct {
+	Tag             types.String            `tfsdk:"tag"`
+	EnrolmentKeyId  types.Int64             `tfsdk:"enrolment_key_id"`
+	HostnamePattern types.String            `tfsdk:"hostname_pattern"`
+	Systems         []SystemDataSourceState `tfsdk:"systems"`
 }
 
 type PolicyAclState struct {
@@ -32,26 +116,32 @@ type PolicyAclState struct {
 }
 
 type DnsZoneState struct {
-	Id    types.Int64  `tfsdk:"id"`
-	Name  types.String `tfsdk:"name"`
-	Notes types.String `tfsdk:"notes"`
+	Id             types.Int64     `tfsdk:"id"`
+	Name           types.String    `tfsdk:"name"`
+	Notes          types.String    `tfsdk:"notes"`
+	OrganisationId types.String    `tfsdk:"organisation_id"`
+	Timeouts       []TimeoutsState `tfsdk:"timeouts"`
 }
 
 type DnsRecordState struct {
-	Id      types.Int64  `tfsdk:"id"`
-	ZoneId  types.Int64  `tfsdk:"zone_id"`
-	Name    types.String `tfsdk:"name"`
-	Tags    []string     `tfsdk:"tags"`
-	Systems []string     `tfsdk:"systems"`
-	Notes   types.String `tfsdk:"notes"`
-	Fqdn    types.String `tfsdk:"fqdn"`
+	Id             types.Int64     `tfsdk:"id"`
+	ZoneId         types.Int64     `tfsdk:"zone_id"`
+	Name           types.String    `tfsdk:"name"`
+	Tags           []string        `tfsdk:"tags"`
+	Systems        []string        `tfsdk:"systems"`
+	Notes          types.String    `tfsdk:"notes"`
+	Fqdn           types.String    `tfsdk:"fqdn"`
+	OrganisationId types.String    `tfsdk:"organisation_id"`
+	Timeouts       []TimeoutsState `tfsdk:"timeouts"`
 }
 
 type TrustRequirementState struct {
-	Id                 types.Int64             `tfsdk:"id"`
-	Description        types.String            `tfsdk:"description"`
-	Notes              types.String            `tfsdk:"notes"`
-	UserAuthentication UserAuthenticationState `tfsdk:"user_authentication"`
+	Id                 types.Int64              `tfsdk:"id"`
+	Description        types.String             `tfsdk:"description"`
+	Notes              types.String             `tfsdk:"notes"`
+	UserAuthentication *UserAuthenticationState `tfsdk:"user_authentication"`
+	OrganisationId     types.String             `tfsdk:"organisation_id"`
+	Timeouts           []TimeoutsState          `tfsdk:"timeouts"`
 }
 
 type UserAuthenticationState struct {
@@ -68,9 +158,41 @@ type TrustRequirementCustomClaimsState struct {
 }
 
 type TagState struct {
-	Ref               types.String  `tfsdk:"ref"`
-	Name              types.String  `tfsdk:"name"`
-	Colour            types.String  `tfsdk:"colour"`
-	Notes             types.String  `tfsdk:"notes"`
-	TrustRequirements []types.Int64 `tfsdk:"trust_requirements"`
+	Ref               types.String    `tfsdk:"ref"`
+	Name              types.String    `tfsdk:"name"`
+	Colour            types.String    `tfsdk:"colour"`
+	Notes             types.String    `tfsdk:"notes"`
+	TrustRequirements []types.Int64   `tfsdk:"trust_requirements"`
+	OrganisationId    types.String    `tfsdk:"organisation_id"`
+	Timeouts          []TimeoutsState `tfsdk:"timeouts"`
+}
+
+type OrganisationState struct {
+	Id                types.String `tfsdk:"id"`
+	Name              types.String `tfsdk:"name"`
+	Role              types.String `tfsdk:"role"`
+	Created           types.String `tfsdk:"created"`
+	Plan              types.Int64  `tfsdk:"plan"`
+	Website           types.String `tfsdk:"website"`
+	Phone             types.String `tfsdk:"phone"`
+	MaxSystems        types.Int64  `tfsdk:"max_systems"`
+	EnrolledSystems   types.Int64  `tfsdk:"enrolled_systems"`
+	UnapprovedSystems types.Int64  `tfsdk:"unapproved_systems"`
+}
+
+type OrganisationSummaryState struct {
+	Id   types.String `tfsdk:"id"`
+	Name types.String `tfsdk:"name"`
+	Role types.String `tfsdk:"role"`
+}
+
+type OrganisationsState struct {
+	Organisations []OrganisationSummaryState `tfsdk:"organisations"`
+}
+
+type TimeoutsState struct {
+	Create types.String `tfsdk:"create"`
+	Read   types.String `tfsdk:"read"`
+	Update types.String `tfsdk:"update"`
+	Delete types.String `tfsdk:"delete"`
 }
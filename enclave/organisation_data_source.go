@@ -0,0 +1,117 @@
+package enclave
+
+import (
+	"context"
+	"time"
+
+	enclaveOrganisation "github.com/enclave-networks/go-enclaveapi/data/organisation"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type organisationDataSourceType struct{}
+
+func (o organisationDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	attributes := organisationSummaryAttributes()
+
+	attributes["created"] = tfsdk.Attribute{
+		Type:     types.StringType,
+		Computed: true,
+	}
+	attributes["plan"] = tfsdk.Attribute{
+		Type:     types.Int64Type,
+		Computed: true,
+	}
+	attributes["website"] = tfsdk.Attribute{
+		Type:     types.StringType,
+		Computed: true,
+	}
+	attributes["phone"] = tfsdk.Attribute{
+		Type:     types.StringType,
+		Computed: true,
+	}
+	attributes["max_systems"] = tfsdk.Attribute{
+		Type:     types.Int64Type,
+		Computed: true,
+	}
+	attributes["enrolled_systems"] = tfsdk.Attribute{
+		Type:     types.Int64Type,
+		Computed: true,
+	}
+	attributes["unapproved_systems"] = tfsdk.Attribute{
+		Type:     types.Int64Type,
+		Computed: true,
+	}
+
+	return tfsdk.Schema{
+		Attributes: attributes,
+	}, nil
+}
+
+// The attributes of an organisation that are known from the token alone, all of them computed
+func organisationSummaryAttributes() map[string]tfsdk.Attribute {
+	return map[string]tfsdk.Attribute{
+		"id": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"name": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"role": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+	}
+}
+
+func (o organisationDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return organisationDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type organisationDataSource struct {
+	provider provider
+}
+
+// Read implements tfsdk.DataSource
+func (o organisationDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !o.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	var org enclaveOrganisation.Organisation
+	err := o.provider.withContext(ctx).api.get("", nil, &org)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading organisation", "Could not read organisation "+string(o.provider.api.org.OrgId), err)
+		return
+	}
+
+	// the role is only known from the token's list of organisations
+	state := OrganisationState{
+		Id:                types.String{Value: string(o.provider.api.org.OrgId)},
+		Name:              types.String{Value: org.Name},
+		Role:              types.String{Value: string(o.provider.api.org.Role)},
+		Created:           types.String{Value: org.Created.Format(time.RFC3339)},
+		Plan:              types.Int64{Value: int64(org.Plan)},
+		Website:           stringValueOrNull(org.Website),
+		Phone:             stringValueOrNull(org.Phone),
+		MaxSystems:        types.Int64{Value: int64(org.MaxSystems)},
+		EnrolledSystems:   types.Int64{Value: org.EnrolledSystems},
+		UnapprovedSystems: types.Int64{Value: org.UnapprovedSystems},
+	}
+
+	diags := resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package enclave
+
+import (
+	"reflect"
+	"testing"
+
+	enclaveData "github.com/enclave-networks/go-enclaveapi/data"
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+)
+
+func TestOrganisationDataSource(t *testing.T) {
+	api := newMockApi(t)
+	api.orgs = append(api.orgs, enclaveData.AccountOrganisation{OrgId: "other-org", OrgName: "Other Org", Role: enclaveData.Admin})
+	api.systems["system1"] = &enrolledSystem{SystemId: "system1", State: enclaveSystem.Connected}
+
+	p := newTestProvider(t, api, map[string]any{"token": mockApiToken, "url": api.server.URL, "organisation_id": "other-org"})
+
+	result, err := p.readDataSource("enclave_organisation", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"id":                 "other-org",
+		"name":               "Other Org",
+		"role":               "Admin",
+		"created":            "2022-01-01T00:00:00Z",
+		"plan":               int64(1),
+		"website":            "https://example.com",
+		"phone":              nil,
+		"max_systems":        int64(100),
+		"enrolled_systems":   int64(1),
+		"unapproved_systems": int64(0),
+	}
+
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("expected %#v, got %#v", expected, result)
+	}
+}
+
+func TestOrganisationsDataSource(t *testing.T) {
+	api := newMockApi(t)
+	api.orgs = append(api.orgs, enclaveData.AccountOrganisation{OrgId: "other-org", OrgName: "Other Org", Role: enclaveData.Admin})
+
+	p := newTestProvider(t, api, map[string]any{"token": mockApiToken, "url": api.server.URL, "organisation_id": mockOrgId})
+
+	result, err := p.readDataSource("enclave_organisations", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []any{
+		map[string]any{"id": mockOrgId, "name": mockOrgName, "role": "Owner"},
+		map[string]any{"id": "other-org", "name": "Other Org", "role": "Admin"},
+	}
+
+	if !reflect.DeepEqual(expected, result["organisations"]) {
+		t.Errorf("expected %#v, got %#v", expected, result["organisations"])
+	}
+}
@@ -0,0 +1,62 @@
+package enclave
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type organisationsDataSourceType struct{}
+
+func (o organisationsDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Attributes: map[string]tfsdk.Attribute{
+			"organisations": {
+				Attributes: tfsdk.ListNestedAttributes(organisationSummaryAttributes()),
+				Computed:   true,
+			},
+		},
+	}, nil
+}
+
+func (o organisationsDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return organisationsDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type organisationsDataSource struct {
+	provider provider
+}
+
+// Read implements tfsdk.DataSource
+func (o organisationsDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !o.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	// the organisations were already listed when the provider picked one to manage
+	state := OrganisationsState{
+		Organisations: []OrganisationSummaryState{},
+	}
+	for _, org := range o.provider.orgs {
+		state.Organisations = append(state.Organisations, OrganisationSummaryState{
+			Id:   types.String{Value: string(org.OrgId)},
+			Name: types.String{Value: org.OrgName},
+			Role: types.String{Value: string(org.Role)},
+		})
+	}
+
+	diags := resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
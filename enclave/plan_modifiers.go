@@ -0,0 +1,74 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Plans the value the api defaults an optional attribute to when it isn't set in config, so the
+// default shows in the plan and state instead of the attribute changing after apply
+type defaultValueModifier struct {
+	value attr.Value
+}
+
+func defaultValue(value attr.Value) tfsdk.AttributePlanModifier {
+	return defaultValueModifier{value: value}
+}
+
+func (m defaultValueModifier) Description(_ context.Context) string {
+	return fmt.Sprintf("defaults to %s when not set", m.value)
+}
+
+func (m defaultValueModifier) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("defaults to `%s` when not set", m.value)
+}
+
+func (m defaultValueModifier) Modify(_ context.Context, req tfsdk.ModifyAttributePlanRequest, resp *tfsdk.ModifyAttributePlanResponse) {
+	// nothing to plan when the resource is being deleted, or the attribute is set
+	if req.Plan.Raw.IsNull() || req.AttributeConfig == nil || !req.AttributeConfig.IsNull() {
+		return
+	}
+
+	resp.AttributePlan = m.value
+}
+
+// Replaces the resource when an attribute the api can't update changes. Unlike tfsdk.RequiresReplace this
+// includes an attribute with a default being removed from config, as the default planned for it is a
+// change too, so it has to come after defaultValue. Strings are compared ignoring case like the api does.
+type requiresReplaceModifier struct{}
+
+func requiresReplace() tfsdk.AttributePlanModifier {
+	return requiresReplaceModifier{}
+}
+
+func (m requiresReplaceModifier) Description(_ context.Context) string {
+	return "the resource is replaced when this changes"
+}
+
+func (m requiresReplaceModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m requiresReplaceModifier) Modify(_ context.Context, req tfsdk.ModifyAttributePlanRequest, resp *tfsdk.ModifyAttributePlanResponse) {
+	// nothing to replace when the resource is being created or deleted
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() || req.AttributeState == nil || req.AttributePlan == nil {
+		return
+	}
+
+	if req.AttributePlan.Equal(req.AttributeState) {
+		return
+	}
+
+	planned, ok := req.AttributePlan.(types.String)
+	current, _ := req.AttributeState.(types.String)
+	if ok && !planned.Null && !planned.Unknown && !current.Null && strings.EqualFold(planned.Value, current.Value) {
+		return
+	}
+
+	resp.RequiresReplace = true
+}
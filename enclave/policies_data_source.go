@@ -0,0 +1,129 @@
+package enclave
+
+import (
+	"context"
+	"strings"
+
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type policiesDataSourceType struct{}
+
+func (p policiesDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Attributes: map[string]tfsdk.Attribute{
+			"sender_tag": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"receiver_tag": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"is_enabled": {
+				Type:     types.BoolType,
+				Optional: true,
+			},
+			"description_contains": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"policies": {
+				Attributes: tfsdk.ListNestedAttributes(policyDataSourceAttributes()),
+				Computed:   true,
+			},
+		},
+	}, nil
+}
+
+func (p policiesDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return policiesDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type policiesDataSource struct {
+	provider provider
+}
+
+// Read implements tfsdk.DataSource
+func (p policiesDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !p.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	var state PoliciesState
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var searchTerm *string
+	if !state.DescriptionContains.Null {
+		searchTerm = &state.DescriptionContains.Value
+	}
+
+	policies, err := listPolicies(p.provider.withContext(ctx), searchTerm)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading policies", "Could not list policies", err)
+		return
+	}
+
+	state.Policies = []PolicyDataSourceState{}
+	for _, policy := range policies {
+		if !policyMatches(policy, state) {
+			continue
+		}
+
+		state.Policies = append(state.Policies, policyDataSourceState(policy, p.provider.organisationId()))
+	}
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Check a policy against the filters set on the data source
+func policyMatches(policy enclavePolicy.Policy, filters PoliciesState) bool {
+	if !filters.SenderTag.Null && !hasTag(policy.SenderTags, filters.SenderTag.Value) {
+		return false
+	}
+
+	if !filters.ReceiverTag.Null && !hasTag(policy.ReceiverTags, filters.ReceiverTag.Value) {
+		return false
+	}
+
+	if !filters.IsEnabled.Null && policy.IsEnabled != filters.IsEnabled.Value {
+		return false
+	}
+
+	// the api search isn't guaranteed to only match on the description
+	if !filters.DescriptionContains.Null &&
+		!strings.Contains(strings.ToLower(policy.Description), strings.ToLower(filters.DescriptionContains.Value)) {
+		return false
+	}
+
+	return true
+}
+
+func hasTag(tags []enclaveTag.TagReference, name string) bool {
+	for _, tag := range tags {
+		if tag.Tag == name {
+			return true
+		}
+	}
+
+	return false
+}
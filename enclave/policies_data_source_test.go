@@ -0,0 +1,96 @@
+package enclave
+
+import (
+	"reflect"
+	"testing"
+
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+)
+
+func TestPoliciesDataSource(t *testing.T) {
+	api := newMockApi(t)
+
+	api.policies[1] = &enclavePolicy.Policy{
+		Id:           1,
+		Description:  "Devs to DB",
+		IsEnabled:    true,
+		SenderTags:   []enclaveTag.TagReference{{Tag: "dev", Ref: "ref1"}},
+		ReceiverTags: []enclaveTag.TagReference{{Tag: "db", Ref: "ref2"}},
+		Acls: []enclavePolicy.PolicyAcl{
+			{Protocol: enclavePolicy.Tcp, Ports: "5432"},
+		},
+	}
+	api.policies[2] = &enclavePolicy.Policy{
+		Id:           2,
+		Description:  "devs to web",
+		IsEnabled:    false,
+		SenderTags:   []enclaveTag.TagReference{{Tag: "dev", Ref: "ref1"}},
+		ReceiverTags: []enclaveTag.TagReference{{Tag: "web", Ref: "ref3"}},
+	}
+	api.policies[3] = &enclavePolicy.Policy{
+		Id:           3,
+		Description:  "web to db",
+		IsEnabled:    true,
+		SenderTags:   []enclaveTag.TagReference{{Tag: "web", Ref: "ref3"}},
+		ReceiverTags: []enclaveTag.TagReference{{Tag: "db", Ref: "ref2"}},
+	}
+
+	p := newTestProvider(t, api, nil)
+
+	testCases := map[string]struct {
+		config   map[string]any
+		expected []int64
+	}{
+		"all":                  {map[string]any{}, []int64{1, 2, 3}},
+		"sender tag":           {map[string]any{"sender_tag": "dev"}, []int64{1, 2}},
+		"receiver tag":         {map[string]any{"receiver_tag": "db"}, []int64{1, 3}},
+		"enabled":              {map[string]any{"is_enabled": true}, []int64{1, 3}},
+		"disabled":             {map[string]any{"is_enabled": false}, []int64{2}},
+		"description contains": {map[string]any{"description_contains": "devs"}, []int64{1, 2}},
+		"combined":             {map[string]any{"sender_tag": "dev", "receiver_tag": "db", "is_enabled": true}, []int64{1}},
+		"no matches":           {map[string]any{"sender_tag": "nobody"}, []int64{}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := p.readDataSource("enclave_policies", testCase.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []int64{}
+			for _, policy := range result["policies"].([]any) {
+				ids = append(ids, policy.(map[string]any)["id"].(int64))
+			}
+
+			if !reflect.DeepEqual(testCase.expected, ids) {
+				t.Errorf("expected policies %v, got %v", testCase.expected, ids)
+			}
+		})
+	}
+
+	result, err := p.readDataSource("enclave_policies", map[string]any{"description_contains": "devs to db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []any{
+		map[string]any{
+			"id":                 int64(1),
+			"description":        "Devs to DB",
+			"notes":              nil,
+			"is_enabled":         true,
+			"sender_tags":        []any{"dev"},
+			"receiver_tags":      []any{"db"},
+			"trust_requirements": nil,
+			"organisation_id":    mockOrgId,
+			"acl": []any{
+				map[string]any{"protocol": "tcp", "ports": "5432", "description": nil},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expected, result["policies"]) {
+		t.Errorf("expected %#v, got %#v", expected, result["policies"])
+	}
+}
@@ -3,21 +3,20 @@ package enclave
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
-	"github.com/hashicorp/terraform-plugin-framework/attr"
-
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 type policyResourceType struct{}
 
 func (p policyResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 1,
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
 				Type:     types.Int64Type,
@@ -34,37 +33,37 @@ func (p policyResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Dia
 			"is_enabled": {
 				Type:     types.BoolType,
 				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					defaultValue(types.Bool{Value: true}),
+				},
 			},
 			"sender_tags": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.StringType,
 				},
 				Optional: true,
 			},
 			"receiver_tags": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.StringType,
 				},
 				Optional: true,
 			},
 			"trust_requirements": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.Int64Type,
 				},
 				Optional: true,
 			},
 			"acl": {
-				Type: types.ListType{
-					ElemType: types.ObjectType{
-						AttrTypes: map[string]attr.Type{
-							"protocol":    types.StringType,
-							"ports":       types.StringType,
-							"description": types.StringType,
-						},
-					},
-				},
-				Optional: true,
+				Attributes: tfsdk.ListNestedAttributes(policyAclAttributes()),
+				Optional:   true,
 			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -97,22 +96,29 @@ func (p policy) Create(ctx context.Context, req tfsdk.CreateResourceRequest, res
 		return
 	}
 
-	var isEnabled bool
-	if plan.IsEnabled.Null {
-		isEnabled = true
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := p.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
+	plan.OrganisationId = org.organisationId()
+
 	// Let's check lengths and add some warnings
 	checkForPolicyWarnings(plan, &resp.Diagnostics)
 
 	policyAcl, err := toPolicyAcl(plan.Acl)
 	if err != nil {
 		resp.Diagnostics.AddError("Error converting ACL", err.Error())
+		return
 	}
 
 	policyCreate := enclavePolicy.PolicyCreate{
 		Description:       plan.Description.Value,
-		IsEnabled:         isEnabled,
+		IsEnabled:         plan.IsEnabled.Value,
 		Notes:             plan.Notes.Value,
 		SenderTags:        plan.SenderTags,
 		ReceiverTags:      plan.ReceiverTags,
@@ -121,17 +127,25 @@ func (p policy) Create(ctx context.Context, req tfsdk.CreateResourceRequest, res
 	}
 
 	// create request
-	policyResponse, err := p.provider.client.Policies.Create(policyCreate)
+	var policyResponse enclavePolicy.Policy
+	err = org.api.do(http.MethodPost, "/policies", nil, policyCreate, &policyResponse)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating Policy in enclave",
-			err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating Policy in enclave", "Could not create policy", err)
 		return
 	}
 
 	setPolicyStateId(policyResponse, &plan)
 
+	policyResponse, err = setPolicyEnabled(org, policyResponse, plan.IsEnabled.Value)
+	if err != nil {
+		// the policy has been created so keep it in state as it is
+		plan.IsEnabled = types.Bool{Value: policyResponse.IsEnabled}
+		resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating Policy in enclave", "Could not "+enableAction(plan.IsEnabled.Value)+" policy "+fmt.Sprint(policyResponse.Id), err)
+		return
+	}
+
 	diags = resp.State.Set(ctx, plan)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
@@ -147,18 +161,32 @@ func (p policy) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *t
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := p.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
 	policyId := enclavePolicy.PolicyId(state.Id.Value)
 
-	currentPolicy, err := p.provider.client.Policies.Get(policyId)
+	var currentPolicy enclavePolicy.Policy
+	err := org.api.get(fmt.Sprintf("/policies/%d", policyId), nil, &currentPolicy)
+	if isNotFound(err) {
+		// the policy has been deleted outside of terraform, remove it so it gets recreated
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error reading policy Id",
-			"Could not read Id "+fmt.Sprint(policyId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading policy Id", "Could not read Id "+fmt.Sprint(policyId), err)
 		return
 	}
 
-	setPolicyStateId(currentPolicy, &state)
+	setPolicyState(currentPolicy, &state)
 	diags = resp.State.Set(ctx, state)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
@@ -180,31 +208,47 @@ func (p policy) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, res
 	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := p.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
 	// Let's check lengths and add some warnings
 	checkForPolicyWarnings(plan, &resp.Diagnostics)
 
 	policyAcl, err := toPolicyAcl(plan.Acl)
 	if err != nil {
 		resp.Diagnostics.AddError("Error converting ACL", err.Error())
+		return
 	}
 
 	policyId := enclavePolicy.PolicyId(state.Id.Value)
 
-	updatePolicy, err := p.provider.client.Policies.Update(policyId, enclavePolicy.PolicyPatch{
-		Description:       plan.Description.Value,
-		IsEnabled:         plan.IsEnabled.Value,
-		SenderTags:        plan.SenderTags,
-		ReceiverTags:      plan.ReceiverTags,
-		Notes:             plan.Notes.Value,
-		Acls:              policyAcl,
-		TrustRequirements: toTrustRequirementIdArray(plan.TrustRequirements),
-	})
+	// is_enabled isn't part of the patch, it's set on its own after
+	var updatePolicy enclavePolicy.Policy
+	err = org.api.do(http.MethodPatch, fmt.Sprintf("/policies/%d", policyId), nil, policyPatch{
+		Description:       &plan.Description.Value,
+		SenderTags:        patchList(plan.SenderTags),
+		ReceiverTags:      patchList(plan.ReceiverTags),
+		Notes:             &plan.Notes.Value,
+		Acls:              patchList(policyAcl),
+		TrustRequirements: patchList(toTrustRequirementIdArray(plan.TrustRequirements)),
+	}, &updatePolicy)
 
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error updating Policy",
-			"Could not read Id "+fmt.Sprint(policyId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating Policy", "Could not update Id "+fmt.Sprint(policyId), err)
+		return
+	}
+
+	updatePolicy, err = setPolicyEnabled(org, updatePolicy, plan.IsEnabled.Value)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating Policy", "Could not "+enableAction(plan.IsEnabled.Value)+" Id "+fmt.Sprint(policyId), err)
 		return
 	}
 
@@ -226,15 +270,22 @@ func (p policy) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, res
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := p.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	policyId := enclavePolicy.PolicyId(state.Id.Value)
 
 	//call api to delete
-	_, err := p.provider.client.Policies.Delete(policyId)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting Policy",
-			"Could not read Id "+fmt.Sprint(policyId)+": "+err.Error(),
-		)
+	err := org.api.do(http.MethodDelete, fmt.Sprintf("/policies/%d", policyId), nil, nil, nil)
+	// already deleted outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error deleting Policy", "Could not delete Id "+fmt.Sprint(policyId), err)
 		return
 	}
 
@@ -242,16 +293,93 @@ func (p policy) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, res
 	resp.State.RemoveResource(ctx)
 }
 
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (p policy) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, p.provider, req, resp)
+}
+
 // Import resource
 func (p policy) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
-	// Save the import identifier in the id attribute
-	tfsdk.ResourceImportStatePassthroughID(ctx, tftypes.NewAttributePath().WithAttributeName("id"), req, resp)
+	importStateInt64Id(ctx, req, resp)
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (p policy) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		// version 0 had lists of tags and trust requirements, which are now sets so their order doesn't matter
+		stateUpgrade{
+			priorSchema: tfsdk.Schema{
+				Attributes: map[string]tfsdk.Attribute{
+					"id":            {Type: types.Int64Type, Computed: true},
+					"description":   {Type: types.StringType, Required: true},
+					"notes":         {Type: types.StringType, Optional: true},
+					"is_enabled":    {Type: types.BoolType, Optional: true, Computed: true},
+					"sender_tags":   {Type: types.ListType{ElemType: types.StringType}, Optional: true},
+					"receiver_tags": {Type: types.ListType{ElemType: types.StringType}, Optional: true},
+					"trust_requirements": {
+						Type:     types.ListType{ElemType: types.Int64Type},
+						Optional: true,
+					},
+					"acl": {
+						Attributes: tfsdk.ListNestedAttributes(map[string]tfsdk.Attribute{
+							"protocol":    {Type: types.StringType, Required: true},
+							"ports":       {Type: types.StringType, Optional: true},
+							"description": {Type: types.StringType, Optional: true},
+						}),
+						Optional: true,
+					},
+					"organisation_id": {Type: types.StringType, Optional: true, Computed: true},
+				},
+				Blocks: map[string]tfsdk.Block{
+					"timeouts": timeoutsBlock(),
+				},
+			},
+			upgrade: listsToSets("sender_tags", "receiver_tags", "trust_requirements"),
+		},
+	)
 }
 
 func setPolicyStateId(policy enclavePolicy.Policy, state *PolicyState) {
 	state.Id = types.Int64{Value: int64(policy.Id)}
 }
 
+// Map the full api policy back into state so out-of-band changes are detected
+func setPolicyState(policy enclavePolicy.Policy, state *PolicyState) {
+	setPolicyStateId(policy, state)
+	state.Description = types.String{Value: policy.Description}
+	state.Notes = stringValueOrNull(policy.Notes)
+	state.SenderTags = fromTagReferences(policy.SenderTags, state.SenderTags)
+	state.ReceiverTags = fromTagReferences(policy.ReceiverTags, state.ReceiverTags)
+	state.Acl = fromPolicyAcl(policy.Acls, state.Acl)
+	state.TrustRequirements = fromUsedTrustRequirements(policy.SenderTrustRequirements, state.TrustRequirements)
+
+	state.IsEnabled = types.Bool{Value: policy.IsEnabled}
+}
+
+// Enable or disable a policy if it isn't already, as the api only enables policies through is_enabled
+// in a create or patch and leaves them alone when it's false
+func setPolicyEnabled(org provider, policy enclavePolicy.Policy, enabled bool) (enclavePolicy.Policy, error) {
+	if policy.IsEnabled == enabled {
+		return policy, nil
+	}
+
+	var updated enclavePolicy.Policy
+	err := org.api.do(http.MethodPut, fmt.Sprintf("/policies/%d/%s", policy.Id, enableAction(enabled)), nil, nil, &updated)
+	if err != nil {
+		return policy, err
+	}
+
+	return updated, nil
+}
+
+func enableAction(enabled bool) string {
+	if enabled {
+		return "enable"
+	}
+
+	return "disable"
+}
+
 func checkForPolicyWarnings(plan PolicyState, diagnostics *diag.Diagnostics) {
 	if len(plan.Acl) == 0 {
 		diagnostics.AddWarning(
@@ -294,6 +422,33 @@ func toPolicyAcl(pacl []PolicyAclState) ([]enclavePolicy.PolicyAcl, error) {
 	return result, nil
 }
 
+// Convert api ACLs to state, keeping the existing protocol casing where it still matches
+func fromPolicyAcl(acls []enclavePolicy.PolicyAcl, current []PolicyAclState) []PolicyAclState {
+	if len(acls) == 0 {
+		return nil
+	}
+
+	result := make([]PolicyAclState, len(acls))
+
+	for i, acl := range acls {
+		protocol := types.String{Value: strings.ToLower(string(acl.Protocol))}
+		if i < len(current) {
+			currentProtocol, err := isValidProtocol(current[i].Protocol.Value)
+			if err == nil && strings.EqualFold(string(currentProtocol), string(acl.Protocol)) {
+				protocol = current[i].Protocol
+			}
+		}
+
+		result[i] = PolicyAclState{
+			Protocol:    protocol,
+			Ports:       stringValueOrNull(acl.Ports),
+			Description: stringValueOrNull(acl.Description),
+		}
+	}
+
+	return result
+}
+
 func isValidProtocol(protocol string) (enclavePolicy.PolicyAclProtocol, error) {
 	switch strings.ToLower(protocol) {
 	case "any":
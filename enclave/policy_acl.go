@@ -12,21 +12,35 @@ type policyAclResourceType struct{}
 
 func (pa policyAclResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
-		Attributes: map[string]tfsdk.Attribute{
-			"protocol": {
-				Type:     types.StringType,
-				Required: true,
-			},
-			"ports": {
-				Type:     types.StringType,
-				Optional: true,
+		Version:    1,
+		Attributes: policyAclAttributes(),
+		DeprecationMessage: "enclave_policy_acl never creates anything in enclave, use the enclave_policy_acl data source " +
+			"or write the acl inline on enclave_policy instead. Removing this resource has no effect on your policies.",
+	}, nil
+}
+
+// The attributes of a single acl, shared by the inline policy acl, the data source and the deprecated resource
+func policyAclAttributes() map[string]tfsdk.Attribute {
+	return map[string]tfsdk.Attribute{
+		"protocol": {
+			Type:     types.StringType,
+			Required: true,
+			Validators: []tfsdk.AttributeValidator{
+				aclProtocolValidator{},
 			},
-			"description": {
-				Type:     types.StringType,
-				Optional: true,
+		},
+		"ports": {
+			Type:     types.StringType,
+			Optional: true,
+			Validators: []tfsdk.AttributeValidator{
+				aclPortsValidator{},
 			},
 		},
-	}, nil
+		"description": {
+			Type:     types.StringType,
+			Optional: true,
+		},
+	}
 }
 
 type policyAcl struct {
@@ -104,6 +118,32 @@ func (pa policyAcl) Update(ctx context.Context, req tfsdk.UpdateResourceRequest,
 	}
 }
 
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (pa policyAcl) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		// version 0 is from before the resource was deprecated and has the same attributes,
+		// only without validation, so it's carried over as is
+		stateUpgrade{
+			priorSchema: tfsdk.Schema{
+				Attributes: map[string]tfsdk.Attribute{
+					"protocol": {
+						Type:     types.StringType,
+						Required: true,
+					},
+					"ports": {
+						Type:     types.StringType,
+						Optional: true,
+					},
+					"description": {
+						Type:     types.StringType,
+						Optional: true,
+					},
+				},
+			},
+		},
+	)
+}
+
 // ImportState implements tfsdk.Resource
 func (pa policyAcl) ImportState(context.Context, tfsdk.ImportResourceStateRequest, *tfsdk.ImportResourceStateResponse) {
 	// Do Nothing
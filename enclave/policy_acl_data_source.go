@@ -0,0 +1,40 @@
+package enclave
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+)
+
+type policyAclDataSourceType struct{}
+
+func (pa policyAclDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Attributes: policyAclAttributes(),
+	}, nil
+}
+
+func (pa policyAclDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return policyAclDataSource{}, nil
+}
+
+// An acl definition that can be shared between policies, it doesn't call the api so
+// doesn't need the provider to be configured
+type policyAclDataSource struct{}
+
+// Read implements tfsdk.DataSource
+func (pa policyAclDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var config PolicyAclState
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
@@ -0,0 +1,54 @@
+package enclave
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPolicyAclDataSource(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	acl, err := p.readDataSource("enclave_policy_acl", map[string]any{
+		"protocol":    "tcp",
+		"ports":       "8000-8080",
+		"description": "web",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"protocol":    "tcp",
+		"ports":       "8000-8080",
+		"description": "web",
+	}
+	if !reflect.DeepEqual(expected, acl) {
+		t.Errorf("expected %#v, got %#v", expected, acl)
+	}
+
+	errorCases := map[string]struct {
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"protocol":       {map[string]any{"protocol": "sctp"}, regexp.MustCompile(`"sctp" is not a valid protocol`)},
+		"not a number":   {map[string]any{"protocol": "tcp", "ports": "http"}, regexp.MustCompile(`"http" is not a number`)},
+		"out of range":   {map[string]any{"protocol": "tcp", "ports": "0"}, regexp.MustCompile(`0 is out of range`)},
+		"open range":     {map[string]any{"protocol": "udp", "ports": "80-"}, regexp.MustCompile(`"" is not a number`)},
+		"backwards":      {map[string]any{"protocol": "udp", "ports": "90-80"}, regexp.MustCompile(`starts after it ends`)},
+		"range too high": {map[string]any{"protocol": "tcp", "ports": "80-70000"}, regexp.MustCompile(`70000 is out of range`)},
+		"port too high":  {map[string]any{"protocol": "tcp", "ports": "70000"}, regexp.MustCompile(`70000 is out of range`)},
+		"list":           {map[string]any{"protocol": "tcp", "ports": "22,abc"}, regexp.MustCompile(`"22,abc" is not a number`)},
+		"icmp ports":     {map[string]any{"protocol": "icmp", "ports": "80"}, regexp.MustCompile(`Ports can't be set for the icmp protocol`)},
+		"any ports":      {map[string]any{"protocol": "ANY", "ports": "80"}, regexp.MustCompile(`Ports can't be set for the any protocol`)},
+	}
+
+	for name, errorCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.readDataSource("enclave_policy_acl", errorCase.config)
+			if err == nil || !errorCase.error.MatchString(err.Error()) {
+				t.Errorf("expected error matching %s, got %v", errorCase.error, err)
+			}
+		})
+	}
+}
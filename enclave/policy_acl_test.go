@@ -0,0 +1,61 @@
+package enclave
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPolicyAclResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_policy_acl",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"protocol": "tcp",
+					"ports":    "22",
+				},
+				expect: map[string]any{
+					"protocol": "tcp",
+					"ports":    "22",
+				},
+			},
+			{
+				config: map[string]any{
+					"protocol":    "tcp",
+					"ports":       "22-23",
+					"description": "ssh",
+				},
+				expect: map[string]any{
+					"ports":       "22-23",
+					"description": "ssh",
+				},
+			},
+			{
+				config: map[string]any{
+					"protocol": "tcp",
+					"ports":    "22-",
+				},
+				expectError: regexp.MustCompile(`Invalid ACL ports`),
+			},
+		},
+	})
+}
+
+func TestPolicyAclResourceUpgradeState(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	upgraded, err := p.upgradeState("enclave_policy_acl", 0, `{"protocol":"TCP","ports":"22","description":null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"protocol":    "TCP",
+		"ports":       "22",
+		"description": nil,
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("expected %#v, got %#v", expected, upgraded)
+	}
+}
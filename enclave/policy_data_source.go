@@ -0,0 +1,214 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+type policyDataSourceType struct{}
+
+func (p policyDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	attributes := policyDataSourceAttributes()
+
+	// a policy is looked up by one of these
+	attributes["id"] = tfsdk.Attribute{
+		Type:     types.Int64Type,
+		Optional: true,
+		Computed: true,
+	}
+	attributes["description"] = tfsdk.Attribute{
+		Type:     types.StringType,
+		Optional: true,
+		Computed: true,
+	}
+
+	return tfsdk.Schema{
+		Attributes: attributes,
+	}, nil
+}
+
+// The attributes of a policy as read by the policy data sources, all of them computed
+func policyDataSourceAttributes() map[string]tfsdk.Attribute {
+	return map[string]tfsdk.Attribute{
+		"id": {
+			Type:     types.Int64Type,
+			Computed: true,
+		},
+		"description": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"notes": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"is_enabled": {
+			Type:     types.BoolType,
+			Computed: true,
+		},
+		"sender_tags": {
+			Type: types.SetType{
+				ElemType: types.StringType,
+			},
+			Computed: true,
+		},
+		"receiver_tags": {
+			Type: types.SetType{
+				ElemType: types.StringType,
+			},
+			Computed: true,
+		},
+		"trust_requirements": {
+			Type: types.SetType{
+				ElemType: types.Int64Type,
+			},
+			Computed: true,
+		},
+		"acl": {
+			Type: types.ListType{
+				ElemType: types.ObjectType{
+					AttrTypes: map[string]attr.Type{
+						"protocol":    types.StringType,
+						"ports":       types.StringType,
+						"description": types.StringType,
+					},
+				},
+			},
+			Computed: true,
+		},
+		"organisation_id": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+	}
+}
+
+func (p policyDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return policyDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type policyDataSource struct {
+	provider provider
+}
+
+// Read implements tfsdk.DataSource
+func (p policyDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !p.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	var config PolicyDataSourceState
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.Id.Null == config.Description.Null {
+		resp.Diagnostics.AddError(
+			"Invalid policy lookup",
+			"Exactly one of \"id\" or \"description\" must be set to find a policy",
+		)
+		return
+	}
+
+	var policy enclavePolicy.Policy
+	if !config.Id.Null {
+		policyId := enclavePolicy.PolicyId(config.Id.Value)
+
+		org := p.provider.withContext(ctx)
+
+		var currentPolicy enclavePolicy.Policy
+		err := org.api.get(fmt.Sprintf("/policies/%d", policyId), nil, &currentPolicy)
+		if isNotFound(err) {
+			resp.Diagnostics.AddAttributeError(
+				tftypes.NewAttributePath().WithAttributeName("id"),
+				"Could not find policy",
+				fmt.Sprintf("No policy has the id %d in organisation %s", policyId, org.organisationId().Value),
+			)
+			return
+		}
+
+		if err != nil {
+			addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading policy", "Could not read Id "+fmt.Sprint(policyId), err)
+			return
+		}
+
+		policy = currentPolicy
+	} else {
+		policies, err := listPolicies(p.provider.withContext(ctx), &config.Description.Value)
+		if err != nil {
+			addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading policies", "Could not list policies", err)
+			return
+		}
+
+		// the search matches on part of the description so filter down to exact matches
+		var matches []enclavePolicy.Policy
+		for _, item := range policies {
+			if item.Description == config.Description.Value {
+				matches = append(matches, item)
+			}
+		}
+
+		if len(matches) != 1 {
+			resp.Diagnostics.AddAttributeError(
+				tftypes.NewAttributePath().WithAttributeName("description"),
+				"Could not find a single policy",
+				fmt.Sprintf("Expected 1 policy with the description %q, found %d", config.Description.Value, len(matches)),
+			)
+			return
+		}
+
+		policy = matches[0]
+	}
+
+	diags = resp.State.Set(ctx, policyDataSourceState(policy, p.provider.organisationId()))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Map an api policy into the state the policy data sources read it into
+func policyDataSourceState(policy enclavePolicy.Policy, orgId types.String) PolicyDataSourceState {
+	var state PolicyState
+	setPolicyState(policy, &state)
+
+	return PolicyDataSourceState{
+		Id:                state.Id,
+		Description:       state.Description,
+		Notes:             state.Notes,
+		IsEnabled:         state.IsEnabled,
+		SenderTags:        state.SenderTags,
+		ReceiverTags:      state.ReceiverTags,
+		Acl:               state.Acl,
+		TrustRequirements: state.TrustRequirements,
+		OrganisationId:    orgId,
+	}
+}
+
+// Get every policy in the organisation, optionally filtered by a search term
+func listPolicies(p provider, searchTerm *string) ([]enclavePolicy.Policy, error) {
+	query := url.Values{}
+	query.Set("include_disabled", "true")
+	if searchTerm != nil {
+		query.Set("search", *searchTerm)
+	}
+
+	return listAll[enclavePolicy.Policy](p.api, "/policies", query)
+}
@@ -0,0 +1,101 @@
+package enclave
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
+)
+
+func TestPolicyDataSource(t *testing.T) {
+	api := newMockApi(t)
+
+	// more than a page of policies so lookups by description have to paginate
+	for i := 1; i <= listPageSize+20; i++ {
+		api.policies[enclavePolicy.PolicyId(i)] = &enclavePolicy.Policy{
+			Id:          enclavePolicy.PolicyId(i),
+			Description: fmt.Sprintf("policy %d", i),
+			IsEnabled:   true,
+		}
+	}
+
+	api.policies[1000] = &enclavePolicy.Policy{
+		Id:           1000,
+		Description:  "devs to db",
+		Notes:        "managed by the platform team",
+		IsEnabled:    false,
+		SenderTags:   []enclaveTag.TagReference{{Tag: "dev", Ref: "ref1"}},
+		ReceiverTags: []enclaveTag.TagReference{{Tag: "db", Ref: "ref2"}},
+		Acls: []enclavePolicy.PolicyAcl{
+			{Protocol: enclavePolicy.Tcp, Ports: "1433", Description: "sql"},
+		},
+		SenderTrustRequirements: []enclaveTrustRequirement.UsedTrustRequirement{{Id: 7}},
+	}
+
+	api.policies[1001] = &enclavePolicy.Policy{Id: 1001, Description: "duplicate"}
+	api.policies[1002] = &enclavePolicy.Policy{Id: 1002, Description: "duplicate"}
+
+	p := newTestProvider(t, api, nil)
+
+	expected := map[string]any{
+		"id":                 int64(1000),
+		"description":        "devs to db",
+		"notes":              "managed by the platform team",
+		"is_enabled":         false,
+		"sender_tags":        []any{"dev"},
+		"receiver_tags":      []any{"db"},
+		"trust_requirements": []any{int64(7)},
+		"organisation_id":    mockOrgId,
+		"acl": []any{
+			map[string]any{"protocol": "tcp", "ports": "1433", "description": "sql"},
+		},
+	}
+
+	byId, err := p.readDataSource("enclave_policy", map[string]any{"id": 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, byId) {
+		t.Errorf("expected %#v, got %#v", expected, byId)
+	}
+
+	byDescription, err := p.readDataSource("enclave_policy", map[string]any{"description": "devs to db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, byDescription) {
+		t.Errorf("expected %#v, got %#v", expected, byDescription)
+	}
+
+	errorCases := map[string]struct {
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"neither": {map[string]any{}, regexp.MustCompile(`Exactly one of "id" or "description"`)},
+		"both":    {map[string]any{"id": 1000, "description": "devs to db"}, regexp.MustCompile(`Exactly one of "id" or "description"`)},
+		"missing": {map[string]any{"id": 5000}, regexp.MustCompile(`AttributeName\("id"\): Could not find policy: No policy has the id 5000 in organisation mock-org`)},
+		"none":    {map[string]any{"description": "devs"}, regexp.MustCompile(`found 0`)},
+		"many":    {map[string]any{"description": "duplicate"}, regexp.MustCompile(`found 2`)},
+	}
+
+	for name, errorCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.readDataSource("enclave_policy", errorCase.config)
+			if err == nil || !errorCase.error.MatchString(err.Error()) {
+				t.Errorf("expected error matching %s, got %v", errorCase.error, err)
+			}
+		})
+	}
+
+	// anything else the api responds with is reported as it is
+	api.failures = []int{http.StatusForbidden}
+	_, err = p.readDataSource("enclave_policy", map[string]any{"id": 1000})
+	if err == nil || !regexp.MustCompile(`Could not read Id 1000: the Enclave API responded with 403 Forbidden\. The token doesn't have permission`).MatchString(err.Error()) {
+		t.Errorf("expected the api error, got %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package enclave
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestPolicyResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_policy",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description":   "devs to db",
+					"notes":         "some notes",
+					"sender_tags":   []string{"dev"},
+					"receiver_tags": []string{"db"},
+					"acl": []any{
+						map[string]any{"protocol": "tcp", "ports": "1433", "description": "sql"},
+					},
+				},
+				expect: map[string]any{
+					"description":   "devs to db",
+					"notes":         "some notes",
+					"is_enabled":    true,
+					"sender_tags":   []string{"dev"},
+					"receiver_tags": []string{"db"},
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					policy := api.policies[enclavePolicy.PolicyId(state["id"].(int64))]
+					if policy == nil || !policy.IsEnabled || policy.Acls[0].Protocol != enclavePolicy.Tcp {
+						t.Errorf("unexpected policy in api: %#v", policy)
+					}
+				},
+			},
+			{
+				config: map[string]any{
+					"description":   "devs to db and cache",
+					"notes":         "more notes",
+					"sender_tags":   []string{"dev", "ops"},
+					"receiver_tags": []string{"db", "cache"},
+					"acl": []any{
+						map[string]any{"protocol": "TCP", "ports": "1433"},
+						map[string]any{"protocol": "icmp"},
+					},
+				},
+				expect: map[string]any{
+					"description":   "devs to db and cache",
+					"sender_tags":   []string{"dev", "ops"},
+					"receiver_tags": []string{"db", "cache"},
+					"acl": []any{
+						map[string]any{"protocol": "TCP", "ports": "1433"},
+						map[string]any{"protocol": "icmp"},
+					},
+				},
+			},
+			{
+				// changed in the portal, the next apply puts it back
+				preConfig: func(api *mockApi) {
+					for _, policy := range api.policies {
+						policy.Description = "changed in the portal"
+						policy.ReceiverTags = nil
+						policy.IsEnabled = false
+					}
+				},
+				config: map[string]any{
+					"description":   "devs to db and cache",
+					"notes":         "more notes",
+					"sender_tags":   []string{"dev", "ops"},
+					"receiver_tags": []string{"db", "cache"},
+					"acl": []any{
+						map[string]any{"protocol": "tcp", "ports": "1433"},
+						map[string]any{"protocol": "icmp"},
+					},
+				},
+				expect: map[string]any{
+					"description":   "devs to db and cache",
+					"receiver_tags": []string{"db", "cache"},
+					"is_enabled":    true,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					policy := api.policies[enclavePolicy.PolicyId(state["id"].(int64))]
+					if policy == nil || !policy.IsEnabled {
+						t.Errorf("expected the policy to be enabled again: %#v", policy)
+					}
+				},
+			},
+			{
+				// deleted in the portal, the next apply recreates it
+				preConfig: func(api *mockApi) {
+					for id := range api.policies {
+						delete(api.policies, id)
+					}
+				},
+				config: map[string]any{
+					"description":   "devs to db and cache",
+					"notes":         "more notes",
+					"sender_tags":   []string{"dev", "ops"},
+					"receiver_tags": []string{"db", "cache"},
+					"acl": []any{
+						map[string]any{"protocol": "tcp", "ports": "1433"},
+						map[string]any{"protocol": "icmp"},
+					},
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if len(api.policies) != 1 {
+						t.Errorf("expected the policy to be recreated, got %d policies", len(api.policies))
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+		checkDestroy: func(api *mockApi) error {
+			if len(api.policies) != 0 {
+				return fmt.Errorf("%d policies still exist", len(api.policies))
+			}
+			return nil
+		},
+	})
+}
+
+func TestPolicyResourceIsEnabled(t *testing.T) {
+	// not setting is_enabled is the same as setting it to true
+	values := []any{nil, true, false}
+
+	for _, create := range values {
+		for _, update := range values {
+			create, update := create, update
+
+			t.Run(fmt.Sprintf("%v then %v", create, update), func(t *testing.T) {
+				runResourceTest(t, resourceTest{
+					resourceType: "enclave_policy",
+					steps: []resourceTestStep{
+						isEnabledStep(create),
+						isEnabledStep(update),
+					},
+					importStateId: func(state map[string]any) string {
+						return fmt.Sprint(state["id"])
+					},
+				})
+			})
+		}
+	}
+}
+
+func isEnabledStep(isEnabled any) resourceTestStep {
+	expected := isEnabled != false
+
+	return resourceTestStep{
+		config: map[string]any{
+			"description": "devs to db",
+			"is_enabled":  isEnabled,
+		},
+		expect: map[string]any{
+			"is_enabled": expected,
+		},
+		check: func(t *testing.T, api *mockApi, state map[string]any) {
+			policy := api.policies[enclavePolicy.PolicyId(state["id"].(int64))]
+			if policy == nil || policy.IsEnabled != expected {
+				t.Errorf("expected policy in api to have is_enabled %v: %#v", expected, policy)
+			}
+		},
+	}
+}
+
+func TestPolicyResourceAclValidation(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_policy",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description": "bad acl",
+					"acl": []any{
+						map[string]any{"protocol": "tcp", "ports": "22"},
+						map[string]any{"protocol": "tcp", "ports": "70000"},
+					},
+				},
+				expectError: regexp.MustCompile(`AttributeName\("acl"\)\.ElementKeyInt\(1\)\.AttributeName\("ports"\): Invalid ACL ports`),
+			},
+			{
+				config: map[string]any{
+					"description": "bad acl",
+					"acl": []any{
+						map[string]any{"protocol": "gre"},
+					},
+				},
+				expectError: regexp.MustCompile(`AttributeName\("acl"\)\.ElementKeyInt\(0\)\.AttributeName\("protocol"\): Invalid ACL protocol`),
+			},
+			{
+				config: map[string]any{
+					"description": "bad acl",
+					"acl": []any{
+						map[string]any{"protocol": "udp", "ports": "53"},
+						map[string]any{"protocol": "icmp", "ports": "22,abc"},
+						map[string]any{"protocol": "any", "ports": "80"},
+					},
+				},
+				expectError: regexp.MustCompile(`(?s)ElementKeyInt\(1\)\.AttributeName\("ports"\): Invalid ACL ports: "22,abc" is not valid.*ElementKeyInt\(2\)\.AttributeName\("ports"\): Invalid ACL ports: Ports can't be set for the any protocol`),
+			},
+		},
+	})
+}
+
+func TestPolicyResourceApiValidation(t *testing.T) {
+	config := map[string]any{
+		"description": "devs to db",
+		"sender_tags": []any{"devs"},
+		"acl": []any{
+			map[string]any{"protocol": "tcp", "ports": "5432"},
+			map[string]any{"protocol": "icmp"},
+		},
+	}
+
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_policy",
+		steps: []resourceTestStep{
+			{
+				preConfig: func(api *mockApi) {
+					api.validationErrors = map[string][]string{
+						"Acls[1].Protocol": {"ICMP is not allowed between these tags."},
+					}
+				},
+				config:      config,
+				expectError: regexp.MustCompile(`AttributeName\("acl"\)\.ElementKeyInt\(1\)\.AttributeName\("protocol"\): Error creating Policy in enclave: Could not create policy: ICMP is not allowed`),
+			},
+			{
+				preConfig: func(api *mockApi) {
+					// an element of a set has no index so is reported against the set, and a field that isn't an attribute at all
+					api.validationErrors = map[string][]string{
+						"senderTags[0]": {"The tag does not exist."},
+						"Priority":      {"Too many policies."},
+					}
+				},
+				config:      config,
+				expectError: regexp.MustCompile(`(?s)AttributeName\("sender_tags"\): .*The tag does not exist\..*responded with 400 Bad Request\..*One or more validation errors occurred\..*Priority: Too many policies\.`),
+			},
+			{
+				config: config,
+			},
+			{
+				preConfig: func(api *mockApi) {
+					api.validationErrors = map[string][]string{
+						"Description": {"The description is too long."},
+					}
+				},
+				config: map[string]any{
+					"description": "devs to the database",
+					"sender_tags": []any{"devs"},
+				},
+				expectError: regexp.MustCompile(`AttributeName\("description"\): Error updating Policy: Could not update Id \d+: The description is too long\.`),
+			},
+		},
+	})
+}
+
+func TestPolicyResourceUpgradeState(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	// state from before tags were sets, and before organisation_id and timeouts existed
+	upgraded, err := p.upgradeState("enclave_policy", 0, `{
+		"id": 5,
+		"description": "devs to db",
+		"notes": null,
+		"is_enabled": null,
+		"sender_tags": ["dev", "ops", "dev"],
+		"receiver_tags": ["db"],
+		"trust_requirements": null,
+		"acl": [{"protocol": "tcp", "ports": "1433", "description": null}]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"id":                 int64(5),
+		"description":        "devs to db",
+		"notes":              nil,
+		"is_enabled":         nil,
+		"sender_tags":        []any{"dev", "ops"},
+		"receiver_tags":      []any{"db"},
+		"trust_requirements": nil,
+		"acl": []any{
+			map[string]any{"protocol": "tcp", "ports": "1433", "description": nil},
+		},
+		"organisation_id": nil,
+		"timeouts":        nil,
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("expected %#v, got %#v", expected, upgraded)
+	}
+}
+
+func TestPolicyResourceTagOrder(t *testing.T) {
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+
+	config := map[string]any{
+		"description":   "devs to db",
+		"sender_tags":   []string{"dev", "ops"},
+		"receiver_tags": []string{"cache", "db"},
+	}
+
+	typ := p.resourceSchema("enclave_policy").ValueType()
+	state, err := p.apply("enclave_policy", tftypes.NewValue(typ, nil), config)
+	if err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+
+	// the api returning tags in a different order isn't a change
+	for _, policy := range api.policies {
+		policy.SenderTags[0], policy.SenderTags[1] = policy.SenderTags[1], policy.SenderTags[0]
+		policy.ReceiverTags[0], policy.ReceiverTags[1] = policy.ReceiverTags[1], policy.ReceiverTags[0]
+	}
+
+	refreshed, err := p.refresh("enclave_policy", state)
+	if err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+
+	configValue, _ := p.resourceConfig("enclave_policy", config)
+	planned, _, err := p.plan("enclave_policy", refreshed, configValue)
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if !planned.Equal(refreshed) {
+		t.Errorf("expected an empty plan\nstate: %s\nplan:  %s", refreshed, planned)
+	}
+}
+
+func TestPolicyResourceClear(t *testing.T) {
+	config := map[string]any{
+		"description": "devs to db",
+		"sender_tags": []string{"dev"},
+	}
+
+	// whatever is left out of config is cleared in the api, rather than left as it was
+	checkCleared := func(t *testing.T, api *mockApi, state map[string]any) {
+		for _, policy := range api.policies {
+			if policy.Notes != "" || len(policy.ReceiverTags) != 0 || len(policy.Acls) != 0 || len(policy.SenderTrustRequirements) != 0 {
+				t.Errorf("expected notes, receiver tags, acls and trust requirements to be cleared: %#v", policy)
+			}
+		}
+	}
+
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_policy",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description":        "devs to db",
+					"notes":              "some notes",
+					"sender_tags":        []string{"dev"},
+					"receiver_tags":      []string{"db"},
+					"trust_requirements": []int{5},
+					"acl": []any{
+						map[string]any{"protocol": "tcp", "ports": "1433"},
+					},
+				},
+			},
+			{
+				config: config,
+				expect: map[string]any{
+					"notes":              nil,
+					"receiver_tags":      nil,
+					"trust_requirements": nil,
+				},
+				check: checkCleared,
+			},
+			{
+				// added in the portal, the next apply clears them again
+				preConfig: func(api *mockApi) {
+					for _, policy := range api.policies {
+						policy.Notes = "added in the portal"
+						policy.ReceiverTags = api.tagReferences([]string{"db"})
+					}
+				},
+				config: config,
+				expect: map[string]any{
+					"notes":         nil,
+					"receiver_tags": nil,
+				},
+				check: checkCleared,
+			},
+			{
+				// set to empty rather than left out, they stay empty instead of being read back as null
+				config: map[string]any{
+					"description":        "devs to db",
+					"sender_tags":        []string{},
+					"receiver_tags":      []string{},
+					"trust_requirements": []int{},
+				},
+				expect: map[string]any{
+					"sender_tags":        []string{},
+					"receiver_tags":      []string{},
+					"trust_requirements": []int{},
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					checkCleared(t, api, state)
+
+					for _, policy := range api.policies {
+						if len(policy.SenderTags) != 0 {
+							t.Errorf("expected sender tags to be cleared: %#v", policy)
+						}
+					}
+				},
+			},
+		},
+	})
+}
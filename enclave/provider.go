@@ -2,12 +2,17 @@ package enclave
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	enclaveData "github.com/enclave-networks/go-enclaveapi/data"
-	"github.com/enclave-networks/go-enclaveapi/enclave"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 func New() tfsdk.Provider {
@@ -16,32 +21,54 @@ func New() tfsdk.Provider {
 
 type provider struct {
 	configured bool
-	client     *enclave.OrganisationClient
+	api        *apiClient
+	orgs       []enclaveData.AccountOrganisation
 }
 
 func (p *provider) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
 		Attributes: map[string]tfsdk.Attribute{
 			"token": {
-				Type:     types.StringType,
-				Required: true,
+				Type:      types.StringType,
+				Optional:  true,
+				Sensitive: true,
 			},
 			"organisation_id": {
 				Type:     types.StringType,
 				Optional: true,
 			},
+			"organisation_name": {
+				Type:     types.StringType,
+				Optional: true,
+			},
 			"url": {
 				Type:     types.StringType,
 				Optional: true,
 			},
+			"profile": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"max_retries": {
+				Type:     types.Int64Type,
+				Optional: true,
+			},
+			"request_timeout": {
+				Type:     types.StringType,
+				Optional: true,
+			},
 		},
 	}, nil
 }
 
 type providerData struct {
-	Token          types.String `tfsdk:"token"`
-	OrganisationId types.String `tfsdk:"organisation_id"`
-	Url            types.String `tfsdk:"url"`
+	Token            types.String `tfsdk:"token"`
+	OrganisationId   types.String `tfsdk:"organisation_id"`
+	OrganisationName types.String `tfsdk:"organisation_name"`
+	Url              types.String `tfsdk:"url"`
+	Profile          types.String `tfsdk:"profile"`
+	MaxRetries       types.Int64  `tfsdk:"max_retries"`
+	RequestTimeout   types.String `tfsdk:"request_timeout"`
 }
 
 func (p *provider) Configure(ctx context.Context, req tfsdk.ConfigureProviderRequest, resp *tfsdk.ConfigureProviderResponse) {
@@ -53,89 +80,121 @@ func (p *provider) Configure(ctx context.Context, req tfsdk.ConfigureProviderReq
 		return
 	}
 
-	var token string
-	if config.Token.Unknown {
+	if config.Token.Unknown || config.OrganisationId.Unknown || config.OrganisationName.Unknown || config.Url.Unknown || config.Profile.Unknown ||
+		config.MaxRetries.Unknown || config.RequestTimeout.Unknown {
 		// Cannot connect to client with an unknown value
 		resp.Diagnostics.AddWarning(
 			"Unable to create client",
-			"Cannot use unknown value as token",
+			"Cannot use unknown values to configure the provider",
 		)
 		return
 	}
 
-	if !config.Token.Null {
-		token = config.Token.Value
+	settings, diags := resolveProviderSettings(config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
+	token := settings.Token.Value
 	if token == "" {
 		// Error vs warning - empty value must stop execution
 		resp.Diagnostics.AddError(
 			"Unable to find token",
-			"Token cannot be an empty string",
+			"Set the \"token\" provider field, the ENCLAVE_TOKEN environment variable, "+
+				"or a token in a profile of the credentials file (~/.enclave/credentials by default)",
 		)
 		return
 	}
 
-	var organisationId string
-	if config.OrganisationId.Unknown {
-		// Cannot connect to client with an unknown value
-		resp.Diagnostics.AddWarning(
-			"Unable to create client",
-			"Cannot use unknown value as organisation",
-		)
-		return
-	}
-
-	if !config.Token.Null {
-		organisationId = config.OrganisationId.Value
-	}
+	tflog.Info(ctx, "Configuring enclave provider", map[string]interface{}{
+		"token_source":             settings.Token.Source,
+		"organisation_id_source":   settings.OrganisationId.Source,
+		"organisation_name_source": settings.OrganisationName.Source,
+		"url_source":               settings.Url.Source,
+	})
 
-	var c *enclave.Client
-	if !config.Url.Null {
-		c, _ = enclave.NewWithUrl(token, config.Url.Value)
-	} else {
-		c = enclave.New(token)
+	maxRetries, requestTimeout, diags := retrySettings(config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	orgs, err := c.GetOrgs()
+	api, err := newApiClient(token, settings.Url.Value, maxRetries, requestTimeout)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Error getting enclave orgs",
-			"Please ensure you have a valid Token",
+			"Unable to create client",
+			"Please ensure the url from "+settings.Url.Source+" is a valid url: "+err.Error(),
 		)
 		return
 	}
 
-	if len(orgs) > 1 && organisationId == "" {
-		resp.Diagnostics.AddError(
-			"Error more than one Enclave Organisation is available with this token",
-			"Please set the \"organisation_id\" provider field",
-		)
+	root := *api
+	root.ctx = ctx
+	orgs, err := root.getOrgs()
+	if err != nil {
+		addApiError(&resp.Diagnostics, tfsdk.Schema{}, "Error getting enclave orgs", "Please ensure the token from "+settings.Token.Source+" is valid", err)
 		return
 	}
 
-	var currentOrg enclaveData.AccountOrganisation
-	if len(orgs) == 1 {
-		currentOrg = orgs[0]
-	} else {
-		for _, org := range orgs {
-			if string(org.OrgId) == organisationId {
-				currentOrg = org
-				break
-			}
+	currentOrg, diags := selectOrganisation(orgs, settings)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	api.org = currentOrg
+	p.api = api
+	p.orgs = orgs
+	p.configured = true
+}
+
+// Get a copy of the provider that manages the organisation with the given id, using the same token,
+// with its api calls cancelled along with ctx. A null or unknown id gets the provider's own organisation.
+func (p provider) forOrganisation(ctx context.Context, orgId types.String) (provider, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if !p.configured || orgId.Null || orgId.Unknown || orgId.Value == string(p.api.org.OrgId) {
+		return p.withContext(ctx), diags
+	}
+
+	for _, org := range p.orgs {
+		if string(org.OrgId) != orgId.Value {
+			continue
 		}
+
+		api := *p.api
+		api.org = org
+		p.api = &api
+
+		return p.withContext(ctx), diags
 	}
 
-	if currentOrg == (enclaveData.AccountOrganisation{}) {
-		resp.Diagnostics.AddError(
-			"Could not find org",
-			"Please ensure you have specified the correct org and you have access to it",
-		)
-		return
+	diags.AddAttributeError(
+		tftypes.NewAttributePath().WithAttributeName("organisation_id"),
+		"Could not find org",
+		fmt.Sprintf("No organisation available to this token has the id %q, "+
+			"please ensure you have specified the correct org and you have access to it", orgId.Value),
+	)
+	return p, diags
+}
+
+// Get a copy of the provider whose api calls are cancelled along with ctx
+func (p provider) withContext(ctx context.Context) provider {
+	if !p.configured {
+		return p
 	}
 
-	p.client = c.CreateOrganisationClient(currentOrg)
-	p.configured = true
+	api := *p.api
+	api.ctx = ctx
+
+	p.api = &api
+	return p
+}
+
+// The id of the organisation the provider manages, in state form
+func (p provider) organisationId() types.String {
+	return types.String{Value: string(p.api.org.OrgId)}
 }
 
 // GetResources - Defines provider resources
@@ -148,11 +207,119 @@ func (p *provider) GetResources(_ context.Context) (map[string]tfsdk.ResourceTyp
 		"enclave_dns_record":        dnsRecordResourceType{},
 		"enclave_trust_requirement": trustRequirementResourceType{},
 		"enclave_tag":               tagResourceType{},
+		"enclave_system":            systemResourceType{},
+		"enclave_system_approval":   systemApprovalResourceType{},
 		// Add more resource types here
 	}, nil
 }
 
 // GetDataSources - Defines provider data sources
 func (p *provider) GetDataSources(_ context.Context) (map[string]tfsdk.DataSourceType, diag.Diagnostics) {
-	return map[string]tfsdk.DataSourceType{}, nil
+	return map[string]tfsdk.DataSourceType{
+		"enclave_policy":        policyDataSourceType{},
+		"enclave_policies":      policiesDataSourceType{},
+		"enclave_policy_acl":    policyAclDataSourceType{},
+		"enclave_tag":           tagDataSourceType{},
+		"enclave_tags":          tagsDataSourceType{},
+		"enclave_systems":       systemsDataSourceType{},
+		"enclave_organisation":  organisationDataSourceType{},
+		"enclave_organisations": organisationsDataSourceType{},
+		// Add more data source types here
+	}, nil
+}
+
+// Get the retry settings from the provider block, using the defaults for any that aren't set
+func retrySettings(config providerData) (int, time.Duration, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	maxRetries := defaultMaxRetries
+	if !config.MaxRetries.Null {
+		if config.MaxRetries.Value < 0 {
+			diags.AddAttributeError(
+				tftypes.NewAttributePath().WithAttributeName("max_retries"),
+				"Invalid max_retries",
+				fmt.Sprintf("max_retries can't be negative, got %d", config.MaxRetries.Value),
+			)
+		}
+
+		maxRetries = int(config.MaxRetries.Value)
+	}
+
+	requestTimeout := defaultRequestTimeout
+	if !config.RequestTimeout.Null {
+		var err error
+		requestTimeout, err = time.ParseDuration(config.RequestTimeout.Value)
+		if err != nil || requestTimeout <= 0 {
+			diags.AddAttributeError(
+				tftypes.NewAttributePath().WithAttributeName("request_timeout"),
+				"Invalid request_timeout",
+				fmt.Sprintf("%q is not a positive duration such as \"30s\"", config.RequestTimeout.Value),
+			)
+		}
+	}
+
+	return maxRetries, requestTimeout, diags
+}
+
+// Pick the organisation to manage from those the token can access, by id or name if either is set
+func selectOrganisation(orgs []enclaveData.AccountOrganisation, settings providerSettings) (enclaveData.AccountOrganisation, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if settings.OrganisationId.Value == "" && settings.OrganisationName.Value == "" {
+		if len(orgs) != 1 {
+			diags.AddError(
+				"Error more than one Enclave Organisation is available with this token",
+				"Please set the \"organisation_id\" or \"organisation_name\" provider field, "+
+					"or the ENCLAVE_ORGANISATION_ID or ENCLAVE_ORGANISATION_NAME environment variable",
+			)
+			return enclaveData.AccountOrganisation{}, diags
+		}
+
+		return orgs[0], diags
+	}
+
+	if settings.OrganisationId.Value != "" {
+		for _, org := range orgs {
+			if string(org.OrgId) == settings.OrganisationId.Value {
+				return org, diags
+			}
+		}
+
+		diags.AddError(
+			"Could not find org",
+			"Please ensure you have specified the correct org and you have access to it, "+
+				"the organisation_id was read from "+settings.OrganisationId.Source,
+		)
+		return enclaveData.AccountOrganisation{}, diags
+	}
+
+	var matches []enclaveData.AccountOrganisation
+	for _, org := range orgs {
+		if org.OrgName == settings.OrganisationName.Value {
+			matches = append(matches, org)
+		}
+	}
+
+	if len(matches) == 1 {
+		return matches[0], diags
+	}
+
+	names := make([]string, len(orgs))
+	for i, org := range orgs {
+		names[i] = fmt.Sprintf("%q", org.OrgName)
+	}
+	sort.Strings(names)
+
+	problem := "No organisation"
+	if len(matches) > 1 {
+		problem = fmt.Sprintf("%d organisations", len(matches))
+	}
+
+	diags.AddError(
+		"Could not find org",
+		fmt.Sprintf("%s available to this token is named %q, the organisation_name was read from %s. "+
+			"Use \"organisation_id\" if more than one organisation has the same name. The organisations available are: %s",
+			problem, settings.OrganisationName.Value, settings.OrganisationName.Source, strings.Join(names, ", ")),
+	)
+	return enclaveData.AccountOrganisation{}, diags
 }
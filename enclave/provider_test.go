@@ -0,0 +1,1177 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	enclaveData "github.com/enclave-networks/go-enclaveapi/data"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The tests in this package drive the provider through the same protocol calls terraform makes,
+// against an in-memory mockApi, so they need neither network access nor a terraform binary.
+//
+// That means apply and plan re-implement the part of terraform core that plans a resource, and only as
+// much of it as the provider has needed so far:
+//   - proposedNewState only carries computed attributes over from state at the top level of a resource,
+//     nested attributes and blocks are proposed as they are in config
+//   - each test manages a single resource, so there are no references between resources and config
+//     applied never has unknown values in it, only config that's validated on its own can
+//   - the mock api only does what the provider needs, checked against the api docs by hand
+//
+// So a passing test here doesn't prove terraform itself would agree. Anything relying on terraform core
+// beyond that should be checked against a real terraform, e.g. with resource.Test from
+// terraform-plugin-sdk pointed at the mock api.
+
+func TestMain(m *testing.M) {
+	// keep the developer's own enclave settings out of the tests
+	for _, envVar := range []string{"ENCLAVE_TOKEN", "ENCLAVE_ORGANISATION_ID", "ENCLAVE_ORGANISATION_NAME", "ENCLAVE_URL", "ENCLAVE_PROFILE"} {
+		os.Unsetenv(envVar)
+	}
+	os.Setenv("ENCLAVE_CREDENTIALS_FILE", filepath.Join(os.TempDir(), "enclave-provider-test-missing-credentials"))
+
+	os.Exit(m.Run())
+}
+
+type testProvider struct {
+	t      *testing.T
+	api    *mockApi
+	server tfprotov6.ProviderServer
+	schema *tfprotov6.GetProviderSchemaResponse
+}
+
+// Create a provider server and configure it to talk to the mock api
+func newTestProvider(t *testing.T, api *mockApi, config map[string]any) *testProvider {
+	t.Helper()
+
+	server := providerserver.NewProtocol6(New())()
+
+	schema, err := server.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testProvider{t: t, api: api, server: server, schema: schema}
+
+	if config == nil {
+		config = map[string]any{
+			"token":           mockApiToken,
+			"url":             api.server.URL,
+			"organisation_id": mockOrgId,
+		}
+	}
+
+	if err := p.configure(config); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func (p *testProvider) configure(config map[string]any) error {
+	configValue, err := p.dynamicValue(p.schema.Provider, config)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.server.ConfigureProvider(context.Background(), &tfprotov6.ConfigureProviderRequest{
+		Config: configValue,
+	})
+	if err != nil {
+		return err
+	}
+
+	return diagnosticsError(resp.Diagnostics)
+}
+
+func (p *testProvider) resourceSchema(resourceType string) *tfprotov6.Schema {
+	schema, ok := p.schema.ResourceSchemas[resourceType]
+	if !ok {
+		p.t.Fatalf("no schema for resource %s", resourceType)
+	}
+
+	return schema
+}
+
+// Build a resource's config the way terraform does, where blocks missing from the config are empty rather than null
+func (p *testProvider) resourceConfig(resourceType string, config map[string]any) (tftypes.Value, error) {
+	schema := p.resourceSchema(resourceType)
+
+	values := map[string]any{}
+	for _, block := range schema.Block.BlockTypes {
+		values[block.TypeName] = []any{}
+	}
+	for name, value := range config {
+		values[name] = value
+	}
+
+	return toTerraformValue(schema.ValueType(), values)
+}
+
+func (p *testProvider) dynamicValue(schema *tfprotov6.Schema, values map[string]any) (*tfprotov6.DynamicValue, error) {
+	typ := schema.ValueType()
+
+	value, err := toTerraformValue(typ, values)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicValue, err := tfprotov6.NewDynamicValue(typ, value)
+	return &dynamicValue, err
+}
+
+// Plan and apply a resource the way terraform would, prior is null when creating
+func (p *testProvider) apply(resourceType string, prior tftypes.Value, config map[string]any) (tftypes.Value, error) {
+	schema := p.resourceSchema(resourceType)
+	typ := schema.ValueType()
+
+	configValue, err := p.resourceConfig(resourceType, config)
+	if err != nil {
+		return prior, err
+	}
+
+	if err := p.validate(resourceType, configValue); err != nil {
+		return prior, err
+	}
+
+	planned, requiresReplace, err := p.plan(resourceType, prior, configValue)
+	if err != nil {
+		return prior, err
+	}
+
+	if len(requiresReplace) > 0 && !prior.IsNull() {
+		if err := p.destroy(resourceType, prior); err != nil {
+			return prior, err
+		}
+
+		prior = tftypes.NewValue(typ, nil)
+		planned, _, err = p.plan(resourceType, prior, configValue)
+		if err != nil {
+			return prior, err
+		}
+	}
+
+	applyResp, err := p.server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     resourceType,
+		PriorState:   mustDynamicValue(typ, prior),
+		PlannedState: mustDynamicValue(typ, planned),
+		Config:       mustDynamicValue(typ, configValue),
+	})
+	if err != nil {
+		return prior, err
+	}
+	if err := diagnosticsError(applyResp.Diagnostics); err != nil {
+		return prior, err
+	}
+
+	newState, err := applyResp.NewState.Unmarshal(typ)
+	if err != nil {
+		return prior, err
+	}
+
+	// terraform refuses any apply result that doesn't match what was planned
+	if err := checkConsistent(tftypes.NewAttributePath(), planned, newState); err != nil {
+		return newState, fmt.Errorf("provider produced inconsistent result after apply: %w", err)
+	}
+
+	return newState, nil
+}
+
+// Validate a resource's config the way terraform does before planning
+func (p *testProvider) validate(resourceType string, config tftypes.Value) error {
+	typ := p.resourceSchema(resourceType).ValueType()
+
+	resp, err := p.server.ValidateResourceConfig(context.Background(), &tfprotov6.ValidateResourceConfigRequest{
+		TypeName: resourceType,
+		Config:   mustDynamicValue(typ, config),
+	})
+	if err != nil {
+		return err
+	}
+
+	return diagnosticsError(resp.Diagnostics)
+}
+
+func (p *testProvider) plan(resourceType string, prior tftypes.Value, config tftypes.Value) (tftypes.Value, []*tftypes.AttributePath, error) {
+	schema := p.resourceSchema(resourceType)
+	typ := schema.ValueType()
+
+	planResp, err := p.server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         resourceType,
+		PriorState:       mustDynamicValue(typ, prior),
+		ProposedNewState: mustDynamicValue(typ, proposedNewState(schema, prior, config)),
+		Config:           mustDynamicValue(typ, config),
+	})
+	if err != nil {
+		return prior, nil, err
+	}
+	if err := diagnosticsError(planResp.Diagnostics); err != nil {
+		return prior, nil, err
+	}
+
+	planned, err := planResp.PlannedState.Unmarshal(typ)
+	return planned, planResp.RequiresReplace, err
+}
+
+func (p *testProvider) refresh(resourceType string, state tftypes.Value) (tftypes.Value, error) {
+	typ := p.resourceSchema(resourceType).ValueType()
+
+	resp, err := p.server.ReadResource(context.Background(), &tfprotov6.ReadResourceRequest{
+		TypeName:     resourceType,
+		CurrentState: mustDynamicValue(typ, state),
+	})
+	if err != nil {
+		return state, err
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return state, err
+	}
+
+	return resp.NewState.Unmarshal(typ)
+}
+
+func (p *testProvider) importState(resourceType string, id string) (tftypes.Value, error) {
+	typ := p.resourceSchema(resourceType).ValueType()
+
+	resp, err := p.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: resourceType,
+		ID:       id,
+	})
+	if err != nil {
+		return tftypes.Value{}, err
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return tftypes.Value{}, err
+	}
+	if len(resp.ImportedResources) != 1 {
+		return tftypes.Value{}, fmt.Errorf("expected 1 imported resource, got %d", len(resp.ImportedResources))
+	}
+
+	imported, err := resp.ImportedResources[0].State.Unmarshal(typ)
+	if err != nil {
+		return tftypes.Value{}, err
+	}
+
+	// terraform always reads straight after importing
+	return p.refresh(resourceType, imported)
+}
+
+// Upgrade state written by an older schema version, rawState is the json terraform stores
+func (p *testProvider) upgradeState(resourceType string, version int64, rawState string) (map[string]any, error) {
+	typ := p.resourceSchema(resourceType).ValueType()
+
+	resp, err := p.server.UpgradeResourceState(context.Background(), &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: resourceType,
+		Version:  version,
+		RawState: &tfprotov6.RawState{JSON: []byte(rawState)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return nil, err
+	}
+
+	state, err := resp.UpgradedState.Unmarshal(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromTerraformValue(state).(map[string]any), nil
+}
+
+func (p *testProvider) readDataSource(dataSourceType string, config map[string]any) (map[string]any, error) {
+	schema, ok := p.schema.DataSourceSchemas[dataSourceType]
+	if !ok {
+		p.t.Fatalf("no schema for data source %s", dataSourceType)
+	}
+
+	typ := schema.ValueType()
+
+	configValue, err := toTerraformValue(typ, config)
+	if err != nil {
+		return nil, err
+	}
+
+	validateResp, err := p.server.ValidateDataResourceConfig(context.Background(), &tfprotov6.ValidateDataResourceConfigRequest{
+		TypeName: dataSourceType,
+		Config:   mustDynamicValue(typ, configValue),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := diagnosticsError(validateResp.Diagnostics); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.server.ReadDataSource(context.Background(), &tfprotov6.ReadDataSourceRequest{
+		TypeName: dataSourceType,
+		Config:   mustDynamicValue(typ, configValue),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return nil, err
+	}
+
+	state, err := resp.State.Unmarshal(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromTerraformValue(state).(map[string]any), nil
+}
+
+func (p *testProvider) destroy(resourceType string, prior tftypes.Value) error {
+	typ := p.resourceSchema(resourceType).ValueType()
+	null := tftypes.NewValue(typ, nil)
+
+	resp, err := p.server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     resourceType,
+		PriorState:   mustDynamicValue(typ, prior),
+		PlannedState: mustDynamicValue(typ, null),
+		Config:       mustDynamicValue(typ, null),
+	})
+	if err != nil {
+		return err
+	}
+
+	return diagnosticsError(resp.Diagnostics)
+}
+
+// Checks whether each step replaced the resource or updated it in place, by comparing its id to the last step's
+type replacementCheck struct {
+	id any
+}
+
+func (c *replacementCheck) expect(replaced bool) func(t *testing.T, api *mockApi, state map[string]any) {
+	return func(t *testing.T, api *mockApi, state map[string]any) {
+		t.Helper()
+
+		if c.id != nil && (state["id"] != c.id) != replaced {
+			t.Errorf("expected replaced to be %v, id went from %v to %v", replaced, c.id, state["id"])
+		}
+		c.id = state["id"]
+	}
+}
+
+// A single apply of a resourceTest, similar to resource.TestStep in the sdk
+type resourceTestStep struct {
+	// Run before the step, used to make out-of-band changes to the mock api
+	preConfig func(api *mockApi)
+
+	config map[string]any
+
+	// Attribute values expected in state after the apply
+	expect map[string]any
+
+	check func(t *testing.T, api *mockApi, state map[string]any)
+
+	expectError *regexp.Regexp
+}
+
+// Similar to resource.TestCase in the sdk; applies each step in order, checks the plan is empty
+// after every step, imports the final state if importStateId is set, then destroys it.
+type resourceTest struct {
+	resourceType string
+	steps        []resourceTestStep
+
+	// Run before the provider is configured, used to set up the organisations available
+	setup func(api *mockApi)
+
+	importStateId           func(state map[string]any) string
+	importStateVerifyIgnore []string
+
+	checkDestroy func(api *mockApi) error
+}
+
+func runResourceTest(t *testing.T, test resourceTest) {
+	t.Helper()
+
+	api := newMockApi(t)
+	if test.setup != nil {
+		test.setup(api)
+	}
+
+	p := newTestProvider(t, api, nil)
+
+	typ := p.resourceSchema(test.resourceType).ValueType()
+	state := tftypes.NewValue(typ, nil)
+
+	for i, step := range test.steps {
+		if step.preConfig != nil {
+			step.preConfig(api)
+		}
+
+		var err error
+		if !state.IsNull() {
+			state, err = p.refresh(test.resourceType, state)
+			if err != nil {
+				t.Fatalf("step %d: refresh: %s", i+1, err)
+			}
+		}
+
+		newState, err := p.apply(test.resourceType, state, step.config)
+		if step.expectError != nil {
+			if err == nil {
+				t.Fatalf("step %d: expected error matching %s, got none", i+1, step.expectError)
+			}
+			if !step.expectError.MatchString(err.Error()) {
+				t.Fatalf("step %d: expected error matching %s, got: %s", i+1, step.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("step %d: apply: %s", i+1, err)
+		}
+		state = newState
+
+		values := fromTerraformValue(state).(map[string]any)
+		for name, expected := range step.expect {
+			expectedValue, err := toTerraformValue(typ.(tftypes.Object).AttributeTypes[name], expected)
+			if err != nil {
+				t.Fatalf("step %d: %s: %s", i+1, name, err)
+			}
+
+			if actual := values[name]; !reflect.DeepEqual(fromTerraformValue(expectedValue), actual) {
+				t.Errorf("step %d: expected %s to be %#v, got %#v", i+1, name, expected, actual)
+			}
+		}
+
+		if step.check != nil {
+			step.check(t, api, values)
+		}
+
+		// the same config straight after an apply must not plan any changes
+		refreshed, err := p.refresh(test.resourceType, state)
+		if err != nil {
+			t.Fatalf("step %d: refresh: %s", i+1, err)
+		}
+
+		configValue, _ := p.resourceConfig(test.resourceType, step.config)
+		planned, requiresReplace, err := p.plan(test.resourceType, refreshed, configValue)
+		if err != nil {
+			t.Fatalf("step %d: plan: %s", i+1, err)
+		}
+		if len(requiresReplace) > 0 || !planned.Equal(refreshed) {
+			t.Fatalf("step %d: expected an empty plan after apply\nstate: %s\nplan:  %s", i+1, refreshed, planned)
+		}
+	}
+
+	if test.importStateId != nil && !state.IsNull() {
+		imported, err := p.importState(test.resourceType, test.importStateId(fromTerraformValue(state).(map[string]any)))
+		if err != nil {
+			t.Fatalf("import: %s", err)
+		}
+
+		expected := fromTerraformValue(state).(map[string]any)
+		actual := fromTerraformValue(imported).(map[string]any)
+
+		// timeouts only ever come from config, so like terraform's own import verification they're never compared
+		for _, name := range append(test.importStateVerifyIgnore, "timeouts") {
+			delete(expected, name)
+			delete(actual, name)
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("import: imported state does not match\nexpected: %#v\nactual:   %#v", expected, actual)
+		}
+	}
+
+	if !state.IsNull() {
+		if err := p.destroy(test.resourceType, state); err != nil {
+			t.Fatalf("destroy: %s", err)
+		}
+	}
+
+	if test.checkDestroy != nil {
+		if err := test.checkDestroy(api); err != nil {
+			t.Fatalf("check destroy: %s", err)
+		}
+	}
+}
+
+// Terraform proposes the config with any unset computed attributes carried over from state. Unlike terraform
+// this stops at the top level attributes, computed attributes inside nested attributes or blocks aren't carried over.
+func proposedNewState(schema *tfprotov6.Schema, prior tftypes.Value, config tftypes.Value) tftypes.Value {
+	if prior.IsNull() {
+		return config
+	}
+
+	var priorValues, configValues map[string]tftypes.Value
+	_ = prior.As(&priorValues)
+	_ = config.As(&configValues)
+
+	proposed := map[string]tftypes.Value{}
+	for name, value := range configValues {
+		proposed[name] = value
+	}
+
+	for _, attribute := range schema.Block.Attributes {
+		if attribute.Computed && configValues[attribute.Name].IsNull() {
+			proposed[attribute.Name] = priorValues[attribute.Name]
+		}
+	}
+
+	return tftypes.NewValue(schema.ValueType(), proposed)
+}
+
+// Every known planned value has to match the applied value
+func checkConsistent(path *tftypes.AttributePath, planned tftypes.Value, applied tftypes.Value) error {
+	if !planned.IsKnown() {
+		return nil
+	}
+
+	if !applied.IsKnown() {
+		return fmt.Errorf("%s: applied value is unknown", path)
+	}
+
+	if planned.IsNull() || applied.IsNull() {
+		if planned.IsNull() != applied.IsNull() {
+			return fmt.Errorf("%s: planned %s, applied %s", path, planned, applied)
+		}
+		return nil
+	}
+
+	switch {
+	case planned.Type().Is(tftypes.Object{}):
+		var plannedValues, appliedValues map[string]tftypes.Value
+		_ = planned.As(&plannedValues)
+		_ = applied.As(&appliedValues)
+		for name, value := range plannedValues {
+			if err := checkConsistent(path.WithAttributeName(name), value, appliedValues[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case planned.Type().Is(tftypes.List{}), planned.Type().Is(tftypes.Set{}):
+		var plannedValues, appliedValues []tftypes.Value
+		_ = planned.As(&plannedValues)
+		_ = applied.As(&appliedValues)
+		if len(plannedValues) != len(appliedValues) {
+			return fmt.Errorf("%s: planned %d elements, applied %d", path, len(plannedValues), len(appliedValues))
+		}
+		if planned.Type().Is(tftypes.Set{}) {
+			if !planned.Equal(applied) {
+				return fmt.Errorf("%s: planned %s, applied %s", path, planned, applied)
+			}
+			return nil
+		}
+		for i := range plannedValues {
+			if err := checkConsistent(path.WithElementKeyInt(i), plannedValues[i], appliedValues[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !planned.Equal(applied) {
+		return fmt.Errorf("%s: planned %s, applied %s", path, planned, applied)
+	}
+
+	return nil
+}
+
+// Build a terraform value of the given type from plain go values, attributes missing from maps are null
+func toTerraformValue(typ tftypes.Type, value any) (tftypes.Value, error) {
+	if value == nil {
+		return tftypes.NewValue(typ, nil), nil
+	}
+
+	if value, ok := value.(tftypes.Value); ok {
+		return value, nil
+	}
+
+	switch {
+	case typ.Is(tftypes.String), typ.Is(tftypes.Bool):
+		return tftypes.NewValue(typ, value), nil
+	case typ.Is(tftypes.Number):
+		switch number := value.(type) {
+		case int:
+			return tftypes.NewValue(typ, big.NewFloat(float64(number))), nil
+		case int64:
+			return tftypes.NewValue(typ, big.NewFloat(float64(number))), nil
+		}
+		return tftypes.NewValue(typ, value), nil
+	case typ.Is(tftypes.List{}), typ.Is(tftypes.Set{}):
+		var elementType tftypes.Type
+		if list, ok := typ.(tftypes.List); ok {
+			elementType = list.ElementType
+		} else {
+			elementType = typ.(tftypes.Set).ElementType
+		}
+
+		elements := reflect.ValueOf(value)
+		if elements.Kind() != reflect.Slice {
+			return tftypes.Value{}, fmt.Errorf("expected a slice for %s, got %T", typ, value)
+		}
+
+		values := make([]tftypes.Value, elements.Len())
+		for i := range values {
+			element, err := toTerraformValue(elementType, elements.Index(i).Interface())
+			if err != nil {
+				return tftypes.Value{}, err
+			}
+			values[i] = element
+		}
+
+		return tftypes.NewValue(typ, values), nil
+	case typ.Is(tftypes.Object{}):
+		attributes, ok := value.(map[string]any)
+		if !ok {
+			return tftypes.Value{}, fmt.Errorf("expected a map for %s, got %T", typ, value)
+		}
+
+		values := map[string]tftypes.Value{}
+		for name, attributeType := range typ.(tftypes.Object).AttributeTypes {
+			attribute, err := toTerraformValue(attributeType, attributes[name])
+			if err != nil {
+				return tftypes.Value{}, fmt.Errorf("%s: %w", name, err)
+			}
+			values[name] = attribute
+		}
+
+		for name := range attributes {
+			if _, ok := typ.(tftypes.Object).AttributeTypes[name]; !ok {
+				return tftypes.Value{}, fmt.Errorf("unknown attribute %s", name)
+			}
+		}
+
+		return tftypes.NewValue(typ, values), nil
+	}
+
+	return tftypes.Value{}, fmt.Errorf("unsupported type %s", typ)
+}
+
+// Convert a terraform value to plain go values, numbers become int64 and sets are sorted for comparison
+func fromTerraformValue(value tftypes.Value) any {
+	if value.IsNull() {
+		return nil
+	}
+
+	if !value.IsKnown() {
+		return "<unknown>"
+	}
+
+	switch {
+	case value.Type().Is(tftypes.String):
+		var s string
+		_ = value.As(&s)
+		return s
+	case value.Type().Is(tftypes.Bool):
+		var b bool
+		_ = value.As(&b)
+		return b
+	case value.Type().Is(tftypes.Number):
+		var n big.Float
+		_ = value.As(&n)
+		i, _ := n.Int64()
+		return i
+	case value.Type().Is(tftypes.List{}), value.Type().Is(tftypes.Set{}):
+		var elements []tftypes.Value
+		_ = value.As(&elements)
+		result := make([]any, len(elements))
+		for i, element := range elements {
+			result[i] = fromTerraformValue(element)
+		}
+		if value.Type().Is(tftypes.Set{}) {
+			sort.Slice(result, func(i, j int) bool {
+				return fmt.Sprint(result[i]) < fmt.Sprint(result[j])
+			})
+		}
+		return result
+	case value.Type().Is(tftypes.Object{}):
+		var attributes map[string]tftypes.Value
+		_ = value.As(&attributes)
+		result := map[string]any{}
+		for name, attribute := range attributes {
+			result[name] = fromTerraformValue(attribute)
+		}
+		return result
+	}
+
+	return value.String()
+}
+
+func mustDynamicValue(typ tftypes.Type, value tftypes.Value) *tfprotov6.DynamicValue {
+	dynamicValue, err := tfprotov6.NewDynamicValue(typ, value)
+	if err != nil {
+		panic(err)
+	}
+
+	return &dynamicValue
+}
+
+func diagnosticsError(diagnostics []*tfprotov6.Diagnostic) error {
+	var messages []string
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == tfprotov6.DiagnosticSeverityError {
+			message := diagnostic.Summary + ": " + diagnostic.Detail
+			if diagnostic.Attribute != nil {
+				message = diagnostic.Attribute.String() + ": " + message
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s", strings.Join(messages, "\n"))
+}
+
+func TestProviderConfigure(t *testing.T) {
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+
+	if err := p.configure(map[string]any{"token": "not-the-token", "url": api.server.URL}); err == nil {
+		t.Fatal("expected an error with an invalid token")
+	}
+
+	api.orgs = append(api.orgs, api.orgs[0])
+	api.orgs[1].OrgId = "other-org"
+
+	err := p.configure(map[string]any{"token": mockApiToken, "url": api.server.URL})
+	if err == nil || !strings.Contains(err.Error(), "organisation_id") {
+		t.Fatalf("expected an error asking for organisation_id, got: %v", err)
+	}
+
+	if err := p.configure(map[string]any{"token": mockApiToken, "url": api.server.URL, "organisation_id": "other-org"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.configure(map[string]any{"token": mockApiToken, "url": api.server.URL, "organisation_id": "missing-org"}); err == nil {
+		t.Fatal("expected an error for an organisation the token can't access")
+	}
+}
+
+func TestProviderConfigureSources(t *testing.T) {
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+
+	credentialsPath := filepath.Join(t.TempDir(), "credentials")
+	credentials := "# enclave credentials\n" +
+		"[default]\n" +
+		"token = " + mockApiToken + "\n" +
+		"url = " + api.server.URL + "\n" +
+		"\n" +
+		"[broken]\n" +
+		"token = not-the-token\n" +
+		"url = " + api.server.URL + "\n"
+	if err := os.WriteFile(credentialsPath, []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	malformedPath := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(malformedPath, []byte("token = outside of a profile\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		env    map[string]string
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"environment": {
+			env:    map[string]string{"ENCLAVE_TOKEN": mockApiToken, "ENCLAVE_URL": api.server.URL, "ENCLAVE_ORGANISATION_ID": mockOrgId},
+			config: map[string]any{},
+		},
+		"config over environment": {
+			env:    map[string]string{"ENCLAVE_TOKEN": "not-the-token", "ENCLAVE_URL": api.server.URL},
+			config: map[string]any{"token": mockApiToken},
+		},
+		"rejected environment token": {
+			env:    map[string]string{"ENCLAVE_TOKEN": "not-the-token", "ENCLAVE_URL": api.server.URL},
+			config: map[string]any{},
+			error:  regexp.MustCompile(`token from the ENCLAVE_TOKEN environment variable is valid: the Enclave API responded with 401 Unauthorized`),
+		},
+		"default profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": credentialsPath},
+			config: map[string]any{},
+		},
+		"malformed credentials file without a profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": malformedPath, "ENCLAVE_TOKEN": mockApiToken, "ENCLAVE_URL": api.server.URL},
+			config: map[string]any{},
+		},
+		"malformed credentials file with a profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": malformedPath, "ENCLAVE_TOKEN": mockApiToken, "ENCLAVE_URL": api.server.URL},
+			config: map[string]any{"profile": "default"},
+			error:  regexp.MustCompile(`line 1: expected a \[profile\] or key = value`),
+		},
+		"no home directory without a profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": "", "HOME": "", "ENCLAVE_TOKEN": mockApiToken, "ENCLAVE_URL": api.server.URL},
+			config: map[string]any{},
+		},
+		"no home directory with a profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": "", "HOME": "", "ENCLAVE_PROFILE": "default", "ENCLAVE_TOKEN": mockApiToken},
+			config: map[string]any{},
+			error:  regexp.MustCompile(`Unable to read credentials profile`),
+		},
+		"environment over default profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": credentialsPath, "ENCLAVE_TOKEN": "not-the-token"},
+			config: map[string]any{},
+			error:  regexp.MustCompile(`token from the ENCLAVE_TOKEN environment variable`),
+		},
+		"named profile over environment": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": credentialsPath, "ENCLAVE_TOKEN": mockApiToken},
+			config: map[string]any{"profile": "broken"},
+			error:  regexp.MustCompile(`token from the "broken" profile in .*credentials is valid`),
+		},
+		"profile from environment": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": credentialsPath, "ENCLAVE_PROFILE": "broken"},
+			config: map[string]any{},
+			error:  regexp.MustCompile(`"broken" profile`),
+		},
+		"missing profile": {
+			env:    map[string]string{"ENCLAVE_CREDENTIALS_FILE": credentialsPath},
+			config: map[string]any{"profile": "missing"},
+			error:  regexp.MustCompile(`"missing" is not in .*credentials, the profiles available are: broken, default`),
+		},
+		"missing credentials file": {
+			config: map[string]any{"profile": "default"},
+			error:  regexp.MustCompile(`there is no credentials file`),
+		},
+		"no token": {
+			config: map[string]any{},
+			error:  regexp.MustCompile(`Unable to find token`),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for envVar, value := range testCase.env {
+				t.Setenv(envVar, value)
+			}
+
+			err := p.configure(testCase.config)
+			if testCase.error == nil && err != nil {
+				t.Fatal(err)
+			}
+			if testCase.error != nil && (err == nil || !testCase.error.MatchString(err.Error())) {
+				t.Fatalf("expected error matching %s, got %v", testCase.error, err)
+			}
+		})
+	}
+}
+
+func TestProviderConfigureOrganisationName(t *testing.T) {
+	api := newMockApi(t)
+	api.orgs = append(api.orgs,
+		enclaveData.AccountOrganisation{OrgId: "other-org", OrgName: "Other Org"},
+		enclaveData.AccountOrganisation{OrgId: "copy-1", OrgName: "Copy"},
+		enclaveData.AccountOrganisation{OrgId: "copy-2", OrgName: "Copy"},
+	)
+
+	p := newTestProvider(t, api, map[string]any{"token": mockApiToken, "url": api.server.URL, "organisation_name": mockOrgName})
+
+	errorCases := map[string]struct {
+		env    map[string]string
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"both": {
+			config: map[string]any{"organisation_id": mockOrgId, "organisation_name": mockOrgName},
+			error:  regexp.MustCompile(`Only one of organisation_id from the provider "organisation_id" attribute or organisation_name from the provider "organisation_name" attribute`),
+		},
+		"missing": {
+			config: map[string]any{"organisation_name": "Missing Org"},
+			error:  regexp.MustCompile(`No organisation available to this token is named "Missing Org".*available are: "Copy", "Copy", "Mock Org", "Other Org"`),
+		},
+		"duplicate": {
+			config: map[string]any{"organisation_name": "Copy"},
+			error:  regexp.MustCompile(`2 organisations available to this token is named "Copy"`),
+		},
+		"missing from environment": {
+			env:    map[string]string{"ENCLAVE_ORGANISATION_NAME": "Missing Org"},
+			config: map[string]any{},
+			error:  regexp.MustCompile(`organisation_name was read from the ENCLAVE_ORGANISATION_NAME environment variable`),
+		},
+	}
+
+	for name, errorCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			for envVar, value := range errorCase.env {
+				t.Setenv(envVar, value)
+			}
+
+			errorCase.config["token"] = mockApiToken
+			errorCase.config["url"] = api.server.URL
+
+			err := p.configure(errorCase.config)
+			if err == nil || !errorCase.error.MatchString(err.Error()) {
+				t.Fatalf("expected error matching %s, got %v", errorCase.error, err)
+			}
+		})
+	}
+
+	// the provider block takes precedence over the environment, even across id and name
+	t.Setenv("ENCLAVE_ORGANISATION_ID", "missing-org")
+	if err := p.configure(map[string]any{"token": mockApiToken, "url": api.server.URL, "organisation_name": "Other Org"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSelectOrganisation(t *testing.T) {
+	orgs := []enclaveData.AccountOrganisation{
+		{OrgId: "org-1", OrgName: "First"},
+		{OrgId: "org-2", OrgName: "Second"},
+	}
+
+	testCases := map[string]struct {
+		settings providerSettings
+		expected string
+	}{
+		"by id":   {providerSettings{OrganisationId: providerSetting{Value: "org-2"}}, "org-2"},
+		"by name": {providerSettings{OrganisationName: providerSetting{Value: "Second"}}, "org-2"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			org, diags := selectOrganisation(orgs, testCase.settings)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+			if string(org.OrgId) != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, org.OrgId)
+			}
+		})
+	}
+
+	// a single organisation is still checked against the name it's asked for
+	_, diags := selectOrganisation(orgs[:1], providerSettings{OrganisationName: providerSetting{Value: "Second"}})
+	if !diags.HasError() {
+		t.Error("expected an error for a name that doesn't match the only organisation")
+	}
+}
+
+func TestResourceOrganisationOverride(t *testing.T) {
+	addOtherOrg := func(api *mockApi) {
+		api.orgs = append(api.orgs, enclaveData.AccountOrganisation{OrgId: "other-org", OrgName: "Other Org"})
+	}
+
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_dns_zone",
+		setup:        addOtherOrg,
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"name":            "staging",
+					"organisation_id": "other-org",
+				},
+				expect: map[string]any{
+					"organisation_id": "other-org",
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if api.orgRequests[mockOrgId] != 0 || api.orgRequests["other-org"] == 0 {
+						t.Errorf("expected every request to go to other-org, got %v", api.orgRequests)
+					}
+				},
+			},
+			{
+				config: map[string]any{
+					"name":            "staging",
+					"organisation_id": "missing-org",
+				},
+				expectError: regexp.MustCompile(`No organisation available to this token has the id "missing-org"`),
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprintf("%s/%d", state["organisation_id"], state["id"])
+		},
+	})
+
+	api := newMockApi(t)
+	addOtherOrg(api)
+	p := newTestProvider(t, api, nil)
+
+	typ := p.resourceSchema("enclave_dns_zone").ValueType()
+
+	// without an override resources are in the provider's organisation
+	state, err := p.apply("enclave_dns_zone", tftypes.NewValue(typ, nil), map[string]any{"name": "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if organisationId := fromTerraformValue(state).(map[string]any)["organisation_id"]; organisationId != mockOrgId {
+		t.Errorf("expected the provider's organisation, got %v", organisationId)
+	}
+
+	testCases := map[string]struct {
+		config          map[string]any
+		requiresReplace bool
+	}{
+		"unchanged":            {map[string]any{"name": "production"}, false},
+		"explicitly unchanged": {map[string]any{"name": "production", "organisation_id": mockOrgId}, false},
+		"moved":                {map[string]any{"name": "production", "organisation_id": "other-org"}, true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config, err := p.resourceConfig("enclave_dns_zone", testCase.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, requiresReplace, err := p.plan("enclave_dns_zone", state, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (len(requiresReplace) > 0) != testCase.requiresReplace {
+				t.Errorf("expected requires replace to be %v, got %v", testCase.requiresReplace, requiresReplace)
+			}
+		})
+	}
+
+	// removing an override moves the resource back to the provider's organisation
+	state, err = p.apply("enclave_dns_zone", state, map[string]any{"name": "production", "organisation_id": "other-org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, _ := p.resourceConfig("enclave_dns_zone", map[string]any{"name": "production"})
+	planned, requiresReplace, err := p.plan("enclave_dns_zone", state, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requiresReplace) == 0 || fromTerraformValue(planned).(map[string]any)["organisation_id"] != mockOrgId {
+		t.Errorf("expected the zone to be replaced in %s, got %s", mockOrgId, planned)
+	}
+}
+
+func TestProviderRetries(t *testing.T) {
+	base := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() {
+		retryBaseDelay = base
+	})
+
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+
+	typ := p.resourceSchema("enclave_tag").ValueType()
+
+	api.failures = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	if _, err := p.apply("enclave_tag", tftypes.NewValue(typ, nil), map[string]any{"name": "servers"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.configure(map[string]any{"token": mockApiToken, "url": api.server.URL, "max_retries": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	api.failures = []int{http.StatusTooManyRequests, http.StatusTooManyRequests}
+	_, err := p.apply("enclave_tag", tftypes.NewValue(typ, nil), map[string]any{"name": "servers"})
+	if err == nil || !strings.Contains(err.Error(), "429 Too Many Requests after 2 attempts") {
+		t.Fatalf("expected the create to fail after 2 attempts, got %v", err)
+	}
+
+	errorCases := map[string]struct {
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"negative retries": {map[string]any{"max_retries": -1}, regexp.MustCompile(`max_retries can't be negative`)},
+		"invalid timeout":  {map[string]any{"request_timeout": "soon"}, regexp.MustCompile(`"soon" is not a positive duration`)},
+		"zero timeout":     {map[string]any{"request_timeout": "0s"}, regexp.MustCompile(`"0s" is not a positive duration`)},
+	}
+
+	for name, errorCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			errorCase.config["token"] = mockApiToken
+			errorCase.config["url"] = api.server.URL
+
+			err := p.configure(errorCase.config)
+			if err == nil || !errorCase.error.MatchString(err.Error()) {
+				t.Fatalf("expected error matching %s, got %v", errorCase.error, err)
+			}
+		})
+	}
+}
+
+func TestResourceTimeouts(t *testing.T) {
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+
+	typ := p.resourceSchema("enclave_tag").ValueType()
+
+	state, err := p.apply("enclave_tag", tftypes.NewValue(typ, nil), map[string]any{
+		"name":     "servers",
+		"timeouts": []any{map[string]any{"create": "1m", "update": "1m"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the operation is given up on once its timeout passes, rather than waiting on the api
+	api.delay = 5 * time.Second
+	started := time.Now()
+	_, err = p.apply("enclave_tag", state, map[string]any{
+		"name":     "web",
+		"timeouts": []any{map[string]any{"update": "50ms"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "context deadline exceeded") {
+		t.Fatalf("expected the update to time out, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the update to stop after its timeout, took %s", elapsed)
+	}
+
+	api.delay = 0
+	for _, timeout := range []string{"soon", "-1m", "0s"} {
+		_, err := p.apply("enclave_tag", state, map[string]any{
+			"name":     "servers",
+			"timeouts": []any{map[string]any{"read": timeout}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "Invalid duration") {
+			t.Errorf("expected %q to be rejected, got %v", timeout, err)
+		}
+	}
+}
+
+// The api doesn't keep tags or trust requirements in any particular order, so wherever their names or ids
+// are read back they have to be sets for the order not to show up as a change
+func TestUnorderedAttributesAreSets(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	var check func(path string, typ tftypes.Type)
+	check = func(path string, typ tftypes.Type) {
+		switch typ := typ.(type) {
+		case tftypes.Object:
+			for name, attributeType := range typ.AttributeTypes {
+				attributePath := path + "." + name
+				unordered := strings.HasSuffix(name, "tags") || name == "trust_requirements"
+				if unordered && !attributeType.Is(tftypes.Set{}) {
+					// the tags data source's search results are a list of whole tags, which is fine
+					if list, ok := attributeType.(tftypes.List); !ok || !list.ElementType.Is(tftypes.Object{}) {
+						t.Errorf("%s: expected a set, got %s", attributePath, attributeType)
+					}
+				}
+				check(attributePath, attributeType)
+			}
+		case tftypes.List:
+			check(path, typ.ElementType)
+		case tftypes.Set:
+			check(path, typ.ElementType)
+		}
+	}
+
+	for resourceType, schema := range p.schema.ResourceSchemas {
+		check(resourceType, schema.ValueType())
+	}
+	for dataSourceType, schema := range p.schema.DataSourceSchemas {
+		check("data."+dataSourceType, schema.ValueType())
+	}
+}
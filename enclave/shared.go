@@ -1,10 +1,151 @@
 package enclave
 
 import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
 	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
+// Number of items to request per page when listing from the api
+const listPageSize = 100
+
+// The organisation_id attribute that lets a resource be managed in an organisation other than the provider's
+func organisationIdAttribute() tfsdk.Attribute {
+	return tfsdk.Attribute{
+		Type:     types.StringType,
+		Optional: true,
+		Computed: true,
+		PlanModifiers: tfsdk.AttributePlanModifiers{
+			tfsdk.RequiresReplace(),
+		},
+	}
+}
+
+// The timeouts block that caps how long each operation on a resource can take, e.g. create = "5m"
+func timeoutsBlock() tfsdk.Block {
+	attributes := map[string]tfsdk.Attribute{}
+	for _, operation := range []string{"create", "read", "update", "delete"} {
+		attributes[operation] = tfsdk.Attribute{
+			Type:     types.StringType,
+			Optional: true,
+			Validators: []tfsdk.AttributeValidator{
+				durationValidator{},
+			},
+		}
+	}
+
+	return tfsdk.Block{
+		NestingMode: tfsdk.BlockNestingModeList,
+		MaxItems:    1,
+		Attributes:  attributes,
+	}
+}
+
+// Get a context for an operation that's cancelled once the timeout set for it in a resource's timeouts
+// block has passed. Without one the operation lasts as long as terraform lets it.
+func operationContext(ctx context.Context, timeouts []TimeoutsState, operation string) (context.Context, context.CancelFunc) {
+	if len(timeouts) == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	var timeout types.String
+	switch operation {
+	case "create":
+		timeout = timeouts[0].Create
+	case "read":
+		timeout = timeouts[0].Read
+	case "update":
+		timeout = timeouts[0].Update
+	case "delete":
+		timeout = timeouts[0].Delete
+	}
+
+	// the validator has already rejected anything that doesn't parse
+	duration, err := time.ParseDuration(timeout.Value)
+	if timeout.Null || timeout.Unknown || err != nil {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, duration)
+}
+
+// Plan the organisation_id of a resource. Without one in config it's the provider's organisation, and the
+// resource is replaced if it was created somewhere else, such as before the provider's organisation changed.
+func planOrganisationId(ctx context.Context, p provider, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	// nothing to plan when the resource is being deleted or the provider can't look up organisations yet
+	if req.Plan.Raw.IsNull() || !p.configured {
+		return
+	}
+
+	path := tftypes.NewAttributePath().WithAttributeName("organisation_id")
+
+	var config types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// a configured organisation is replaced by its plan modifier, but check it exists before apply
+	if !config.Null {
+		_, diags := p.forOrganisation(ctx, config)
+		resp.Diagnostics.Append(diags...)
+		return
+	}
+
+	orgId := p.organisationId()
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path, orgId)...)
+	if resp.Diagnostics.HasError() || req.State.Raw.IsNull() {
+		return
+	}
+
+	var state types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// state from before organisation_id existed has no organisation, and is always the provider's
+	if !state.Null && state.Value != orgId.Value {
+		resp.RequiresReplace = append(resp.RequiresReplace, path)
+	}
+}
+
+// Save the import identifier in the id attribute, ResourceImportStatePassthroughID only works for string attributes
+func importStateInt64Id(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	orgId, rawId := splitImportId(req.ID)
+
+	id, err := strconv.ParseInt(rawId, 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid import id",
+			"Expected a numeric id, optionally prefixed with an organisation id and a slash, got \""+req.ID+"\"",
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("id"), id)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("organisation_id"), orgId)...)
+}
+
+// Split an import identifier of the form <organisation_id>/<id> into its parts,
+// the organisation is null when the identifier is just the id
+func splitImportId(importId string) (types.String, string) {
+	orgId, id, found := strings.Cut(importId, "/")
+	if !found {
+		return types.String{Null: true}, importId
+	}
+
+	return types.String{Value: orgId}, id
+}
+
 func toTrustRequirementIdArray(ids []types.Int64) []enclaveTrustRequirement.TrustRequirementId {
 	output := make([]enclaveTrustRequirement.TrustRequirementId, len(ids))
 	for i, id := range ids {
@@ -13,3 +154,71 @@ func toTrustRequirementIdArray(ids []types.Int64) []enclaveTrustRequirement.Trus
 
 	return output
 }
+
+// Get the Trust Requirement Ids in state form. None are nil so an omitted attribute doesn't drift,
+// unless current is set to an empty set.
+func fromUsedTrustRequirements(trustRequirements []enclaveTrustRequirement.UsedTrustRequirement, current []types.Int64) []types.Int64 {
+	if len(trustRequirements) == 0 {
+		return emptyUnlessNull(current)
+	}
+
+	output := make([]types.Int64, len(trustRequirements))
+	for i, trustRequirement := range trustRequirements {
+		output[i] = types.Int64{Value: int64(trustRequirement.Id)}
+	}
+
+	return output
+}
+
+// Get the tag names from a set of tag references. None are nil so an omitted attribute doesn't drift,
+// unless current is set to an empty set.
+func fromTagReferences(tags []enclaveTag.TagReference, current []string) []string {
+	if len(tags) == 0 {
+		return emptyUnlessNull(current)
+	}
+
+	output := make([]string, len(tags))
+	for i, tag := range tags {
+		output[i] = tag.Tag
+	}
+
+	return output
+}
+
+// An empty set in state form, nil when the attribute it's for is null so it stays null
+func emptyUnlessNull[T any](current []T) []T {
+	if current == nil {
+		return nil
+	}
+
+	return []T{}
+}
+
+// The API returns empty strings for unset values, map those back to null to match an omitted attribute
+func stringValueOrNull(value string) types.String {
+	if value == "" {
+		return types.String{Null: true}
+	}
+
+	return types.String{Value: value}
+}
+
+// The API returns zero for unset values, map those back to null to match an omitted attribute
+func int64ValueOrNull(value int64) types.Int64 {
+	if value == 0 {
+		return types.Int64{Null: true}
+	}
+
+	return types.Int64{Value: value}
+}
+
+// Get the tag names from a set of tag references as a set value, empty rather than null so it
+// can be compared against a configured empty set
+func tagReferencesSet(tags []enclaveTag.TagReference) types.Set {
+	elems := make([]attr.Value, len(tags))
+	for i, tag := range tags {
+		elems[i] = types.String{Value: tag.Tag}
+	}
+
+	return types.Set{ElemType: types.StringType, Elems: elems}
+}
@@ -0,0 +1,546 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+type systemResourceType struct{}
+
+func (s systemResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Version: 1,
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:     types.StringType,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+					tfsdk.RequiresReplace(),
+				},
+			},
+			"hostname": {
+				Type:     types.StringType,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"enrolment_key_id": {
+				Type:     types.Int64Type,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"description": {
+				Type:     types.StringType,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"notes": {
+				Type:     types.StringType,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"tags": {
+				Type: types.SetType{
+					ElemType: types.StringType,
+				},
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"is_enabled": {
+				Type:     types.BoolType,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"is_approved": {
+				Type:     types.BoolType,
+				Optional: true,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"virtual_address": {
+				Type:     types.StringType,
+				Computed: true,
+			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (s systemResourceType) NewResource(_ context.Context, pr tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	return system{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type system struct {
+	provider provider
+}
+
+// ValidateConfig implements tfsdk.ResourceWithValidateConfig
+func (s system) ValidateConfig(ctx context.Context, req tfsdk.ValidateResourceConfigRequest, resp *tfsdk.ValidateResourceConfigResponse) {
+	var config SystemState
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.Id.Unknown || config.Hostname.Unknown || config.EnrolmentKeyId.Unknown {
+		return
+	}
+
+	byId := !config.Id.Null
+	byHostname := !config.Hostname.Null || !config.EnrolmentKeyId.Null
+
+	if byId == byHostname || (byHostname && (config.Hostname.Null || config.EnrolmentKeyId.Null)) {
+		resp.Diagnostics.AddError(
+			"Invalid system lookup",
+			"Either \"id\", or both \"hostname\" and \"enrolment_key_id\", must be set to find the system to manage",
+		)
+	}
+}
+
+func (s system) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	if !s.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before apply, "+
+				"likely because it depends on an unknown value from another resource. "+
+				"This leads to weird stuff happening, so we'd prefer if you didn't do that. Thanks!",
+		)
+		return
+	}
+
+	var plan SystemState
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := s.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
+	// systems are enrolled by the enclave agent, so find the existing one to adopt
+	var current enrolledSystem
+	var approved bool
+	var err error
+	if !plan.Id.Unknown {
+		current, approved, err = getSystem(org, enclaveSystem.SystemId(plan.Id.Value))
+	} else {
+		current, approved, err = findSystem(org, plan.Hostname.Value, enclaveEnrolmentKey.EnrolmentKeyId(plan.EnrolmentKeyId.Value))
+	}
+
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error finding System in enclave", "Could not find system", err)
+		return
+	}
+
+	var state SystemState
+	setSystemState(current, approved, &state)
+
+	diags = s.updateSystem(ctx, org, req.Plan.Schema, state, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func (s system) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var state SystemState
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := s.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
+	systemId := enclaveSystem.SystemId(state.Id.Value)
+
+	current, approved, err := getSystem(org, systemId)
+	if isNotFound(err) {
+		// the system has been revoked outside of terraform, remove it from state
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading system Id", "Could not read Id "+string(systemId), err)
+		return
+	}
+
+	setSystemState(current, approved, &state)
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func (s system) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var state SystemState
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan SystemState
+	diags = req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := s.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
+	diags = s.updateSystem(ctx, org, req.Plan.Schema, state, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Make the changes between the current state and the plan in the organisation the system is in,
+// then read the system back into the plan
+func (s system) updateSystem(ctx context.Context, org provider, schema tfsdk.Schema, state SystemState, plan *SystemState) diag.Diagnostics {
+	var diags diag.Diagnostics
+	systemId := enclaveSystem.SystemId(state.Id.Value)
+	approved := state.IsApproved.Value
+	enabled := state.IsEnabled.Value
+
+	if !plan.IsApproved.Unknown && plan.IsApproved.Value != approved {
+		if approved {
+			diags.AddAttributeError(
+				tftypes.NewAttributePath().WithAttributeName("is_approved"),
+				"Error updating System",
+				"System "+string(systemId)+" has already been approved and can't be unapproved",
+			)
+			return diags
+		}
+
+		err := org.api.do(http.MethodPut, fmt.Sprintf("/unapproved-systems/%s/approve", systemId), nil, nil, nil)
+		if err != nil {
+			addApiError(&diags, schema, "Error approving System", "Could not approve Id "+string(systemId), err)
+			return diags
+		}
+
+		// systems are enabled when they're approved
+		approved = true
+		enabled = true
+	}
+
+	var patch enrolledSystemPatch
+	changed := false
+	if !plan.Description.Unknown && !plan.Description.Equal(state.Description) {
+		patch.Description = &plan.Description.Value
+		changed = true
+	}
+	if !plan.Notes.Unknown && !plan.Notes.Equal(state.Notes) {
+		patch.Notes = &plan.Notes.Value
+		changed = true
+	}
+	if !plan.Tags.Unknown && !plan.Tags.Equal(state.Tags) {
+		tags := []string{}
+		diags.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+		if diags.HasError() {
+			return diags
+		}
+
+		patch.Tags = &tags
+		changed = true
+	}
+
+	route := fmt.Sprintf("/systems/%s", systemId)
+	if !approved {
+		route = fmt.Sprintf("/unapproved-systems/%s", systemId)
+	}
+
+	if changed {
+		if err := org.api.do(http.MethodPatch, route, nil, patch, nil); err != nil {
+			addApiError(&diags, schema, "Error updating System", "Could not update Id "+string(systemId), err)
+			return diags
+		}
+	}
+
+	// systems waiting for approval can't be enabled or disabled until they're approved
+	if approved && !plan.IsEnabled.Unknown && plan.IsEnabled.Value != enabled {
+		action := "disable"
+		if plan.IsEnabled.Value {
+			action = "enable"
+		}
+
+		if err := org.api.do(http.MethodPut, route+"/"+action, nil, nil, nil); err != nil {
+			addApiError(&diags, schema, "Error updating System", "Could not "+action+" Id "+string(systemId), err)
+			return diags
+		}
+	}
+
+	current, approved, err := getSystem(org, systemId)
+	if err != nil {
+		addApiError(&diags, schema, "Error reading system Id", "Could not read Id "+string(systemId), err)
+		return diags
+	}
+
+	setSystemState(current, approved, plan)
+	return diags
+}
+
+func (s system) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var state SystemState
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := s.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	systemId := enclaveSystem.SystemId(state.Id.Value)
+
+	// approved systems are revoked, systems still waiting for approval are declined
+	var err error
+	if state.IsApproved.Value {
+		err = org.api.do(http.MethodDelete, "/systems", nil, enclaveSystem.EnrolledSystemBulkAction{
+			SystemIds: []enclaveSystem.SystemId{systemId},
+		}, nil)
+	} else {
+		err = org.api.do(http.MethodDelete, fmt.Sprintf("/unapproved-systems/%s", systemId), nil, nil, nil)
+	}
+
+	// already revoked outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error revoking System", "Could not revoke Id "+string(systemId), err)
+		return
+	}
+
+	resp.State.RemoveResource(ctx)
+}
+
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (s system) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, s.provider, req, resp)
+	if resp.Diagnostics.HasError() || req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var config, state SystemState
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the hostname and enrolment key only find the system to adopt, and replacing the resource would revoke
+	// the system, which can't be brought back without enrolling it again. They follow the system once it's
+	// adopted, so when the machine is renamed the config has to be changed to match.
+	if !config.Hostname.Null && !config.Hostname.Unknown && config.Hostname.Value != state.Hostname.Value {
+		resp.Diagnostics.AddAttributeError(
+			tftypes.NewAttributePath().WithAttributeName("hostname"),
+			"Cannot change the hostname of a System",
+			fmt.Sprintf("System %s has the hostname %q. hostname is only used to find the system to adopt, "+
+				"set it to %q or find the system by id instead.", state.Id.Value, state.Hostname.Value, state.Hostname.Value),
+		)
+	}
+
+	if !config.EnrolmentKeyId.Null && !config.EnrolmentKeyId.Unknown && config.EnrolmentKeyId.Value != state.EnrolmentKeyId.Value {
+		resp.Diagnostics.AddAttributeError(
+			tftypes.NewAttributePath().WithAttributeName("enrolment_key_id"),
+			"Cannot change the enrolment key of a System",
+			fmt.Sprintf("System %s was enrolled with key %d. enrolment_key_id is only used to find the system to adopt, "+
+				"set it to %d or find the system by id instead.", state.Id.Value, state.EnrolmentKeyId.Value, state.EnrolmentKeyId.Value),
+		)
+	}
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (s system) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		// version 0 had a list of tags, which is now a set as the api doesn't keep them in the configured order
+		stateUpgrade{
+			priorSchema: tfsdk.Schema{
+				Attributes: map[string]tfsdk.Attribute{
+					"id":               {Type: types.StringType, Optional: true, Computed: true},
+					"hostname":         {Type: types.StringType, Optional: true, Computed: true},
+					"enrolment_key_id": {Type: types.Int64Type, Optional: true, Computed: true},
+					"description":      {Type: types.StringType, Optional: true, Computed: true},
+					"notes":            {Type: types.StringType, Optional: true, Computed: true},
+					"tags":             {Type: types.ListType{ElemType: types.StringType}, Optional: true, Computed: true},
+					"is_enabled":       {Type: types.BoolType, Optional: true, Computed: true},
+					"is_approved":      {Type: types.BoolType, Optional: true, Computed: true},
+					"virtual_address":  {Type: types.StringType, Computed: true},
+					"organisation_id":  {Type: types.StringType, Optional: true, Computed: true},
+				},
+				Blocks: map[string]tfsdk.Block{
+					"timeouts": timeoutsBlock(),
+				},
+			},
+			upgrade: listsToSets("tags"),
+		},
+	)
+}
+
+// Import resource
+func (s system) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	orgId, id := splitImportId(req.ID)
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("id"), id)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("organisation_id"), orgId)...)
+}
+
+func setSystemState(system enrolledSystem, approved bool, state *SystemState) {
+	state.Id = types.String{Value: string(system.SystemId)}
+	state.Hostname = types.String{Value: system.Hostname}
+	state.EnrolmentKeyId = types.Int64{Value: int64(system.EnrolmentKeyId)}
+	state.Description = types.String{Value: system.Description}
+	state.Notes = types.String{Value: system.Notes}
+	state.Tags = tagReferencesSet(system.Tags)
+	state.IsApproved = types.Bool{Value: approved}
+	state.VirtualAddress = stringValueOrNull(system.VirtualAddress)
+
+	// systems waiting for approval aren't enabled or disabled yet, so keep what's planned for
+	// when they're approved
+	if approved {
+		state.IsEnabled = types.Bool{Value: system.IsEnabled}
+	} else if state.IsEnabled.Null || state.IsEnabled.Unknown {
+		state.IsEnabled = types.Bool{Value: true}
+	}
+}
+
+// Get a system by id, along with whether it's been approved. Systems waiting for approval
+// aren't listed under /systems so look for them under /unapproved-systems too.
+func getSystem(p provider, systemId enclaveSystem.SystemId) (enrolledSystem, bool, error) {
+	var system enrolledSystem
+	err := p.api.get(fmt.Sprintf("/systems/%s", systemId), nil, &system)
+	if err == nil {
+		return system, true, nil
+	}
+
+	if !isNotFound(err) {
+		return enrolledSystem{}, false, err
+	}
+
+	err = p.api.get(fmt.Sprintf("/unapproved-systems/%s", systemId), nil, &system)
+	if err != nil {
+		return enrolledSystem{}, false, err
+	}
+
+	return system, false, nil
+}
+
+// Find the one system with a hostname that was enrolled using an enrolment key, whether or not it's been approved
+func findSystem(p provider, hostname string, enrolmentKeyId enclaveEnrolmentKey.EnrolmentKeyId) (enrolledSystem, bool, error) {
+	systems, err := listSystems(p, &enrolmentKeyId)
+	if err != nil {
+		return enrolledSystem{}, false, err
+	}
+
+	unapprovedSystems, err := listUnapprovedSystems(p)
+	if err != nil {
+		return enrolledSystem{}, false, err
+	}
+
+	var matches []enrolledSystem
+	approved := false
+	for _, system := range systems {
+		if system.Hostname == hostname && system.EnrolmentKeyId == enrolmentKeyId {
+			matches = append(matches, system)
+			approved = true
+		}
+	}
+	for _, system := range unapprovedSystems {
+		if system.Hostname == hostname && system.EnrolmentKeyId == enrolmentKeyId {
+			matches = append(matches, system)
+			approved = false
+		}
+	}
+
+	if len(matches) != 1 {
+		return enrolledSystem{}, false, fmt.Errorf("expected 1 system with the hostname %q enrolled with key %d, found %d", hostname, enrolmentKeyId, len(matches))
+	}
+
+	return matches[0], approved, nil
+}
+
+// Get every system waiting for approval in the organisation
+func listUnapprovedSystems(p provider) ([]enrolledSystem, error) {
+	return listAll[enrolledSystem](p.api, "/unapproved-systems", url.Values{})
+}
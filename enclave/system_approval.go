@@ -0,0 +1,282 @@
+package enclave
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// How long to wait for a system to appear when no timeout is set
+const defaultSystemApprovalTimeout = 10 * time.Minute
+
+// How often to check for the system while waiting, a var so tests don't have to wait as long
+var systemApprovalPollInterval = 10 * time.Second
+
+type systemApprovalResourceType struct{}
+
+func (s systemApprovalResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Version: 0,
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:     types.StringType,
+				Computed: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.UseStateForUnknown(),
+				},
+			},
+			"hostname": {
+				Type:     types.StringType,
+				Optional: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.RequiresReplace(),
+				},
+			},
+			"enrolment_key_id": {
+				Type:     types.Int64Type,
+				Optional: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					tfsdk.RequiresReplace(),
+				},
+			},
+			"timeout": {
+				Type:     types.StringType,
+				Optional: true,
+				Validators: []tfsdk.AttributeValidator{
+					durationValidator{},
+				},
+				DeprecationMessage: "Use create in the timeouts block instead, which is used over timeout when both are set.",
+			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (s systemApprovalResourceType) NewResource(_ context.Context, pr tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	return systemApproval{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type systemApproval struct {
+	provider provider
+}
+
+// ValidateConfig implements tfsdk.ResourceWithValidateConfig
+func (s systemApproval) ValidateConfig(ctx context.Context, req tfsdk.ValidateResourceConfigRequest, resp *tfsdk.ValidateResourceConfigResponse) {
+	var config SystemApprovalState
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.Hostname.Null && config.EnrolmentKeyId.Null {
+		resp.Diagnostics.AddError(
+			"Invalid system lookup",
+			"At least one of \"hostname\" or \"enrolment_key_id\" must be set to find the system to approve",
+		)
+	}
+}
+
+func (s systemApproval) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	if !s.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before apply, "+
+				"likely because it depends on an unknown value from another resource. "+
+				"This leads to weird stuff happening, so we'd prefer if you didn't do that. Thanks!",
+		)
+		return
+	}
+
+	var plan SystemApprovalState
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := s.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
+	timeout := systemApprovalTimeout(plan)
+
+	system, err := waitForUnapprovedSystem(ctx, org, plan, timeout)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error waiting for System to approve", "Could not find a system to approve", err)
+		return
+	}
+
+	err = org.api.do(http.MethodPut, fmt.Sprintf("/unapproved-systems/%s/approve", system.SystemId), nil, nil, nil)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error approving System", "Could not approve Id "+string(system.SystemId), err)
+		return
+	}
+
+	plan.Id = types.String{Value: string(system.SystemId)}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func (s systemApproval) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var state SystemApprovalState
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := s.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
+	systemId := enclaveSystem.SystemId(state.Id.Value)
+
+	var system enrolledSystem
+	err := org.api.get(fmt.Sprintf("/systems/%s", systemId), nil, &system)
+	if isNotFound(err) {
+		// the approved system has been revoked, remove it so a new system is waited for
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading system Id", "Could not read Id "+string(systemId), err)
+		return
+	}
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func (s systemApproval) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	// only the timeout can change without replacing the approval, and that only matters on create
+	var plan SystemApprovalState
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (s systemApproval) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, s.provider, req, resp)
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (s systemApproval) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	// still on the first version of its schema, so there's nothing to upgrade from yet
+	return stateUpgraders()
+}
+
+func (s systemApproval) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	// an approval can't be undone, the system can be revoked with enclave_system instead
+	resp.State.RemoveResource(ctx)
+}
+
+// How long to wait for the system, the create timeout if there is one, then the deprecated timeout,
+// which have both already been checked to parse
+func systemApprovalTimeout(plan SystemApprovalState) time.Duration {
+	if len(plan.Timeouts) > 0 && !plan.Timeouts[0].Create.Null && !plan.Timeouts[0].Create.Unknown {
+		timeout, _ := time.ParseDuration(plan.Timeouts[0].Create.Value)
+		return timeout
+	}
+
+	if !plan.Timeout.Null && !plan.Timeout.Unknown {
+		timeout, _ := time.ParseDuration(plan.Timeout.Value)
+		return timeout
+	}
+
+	return defaultSystemApprovalTimeout
+}
+
+// Poll the systems waiting for approval until one matches, erroring if more than one matches
+// or none turn up before the timeout
+func waitForUnapprovedSystem(ctx context.Context, p provider, plan SystemApprovalState, timeout time.Duration) (enrolledSystem, error) {
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	timedOut := fmt.Errorf("no matching system was waiting for approval after %s", timeout)
+
+	for {
+		systems, err := listUnapprovedSystems(p.withContext(pollCtx))
+		if err != nil {
+			// the create timeout can run out part way through a request as well as between them
+			if errors.Is(pollCtx.Err(), context.DeadlineExceeded) {
+				return enrolledSystem{}, timedOut
+			}
+			return enrolledSystem{}, err
+		}
+
+		var matches []enrolledSystem
+		for _, system := range systems {
+			if !plan.Hostname.Null && system.Hostname != plan.Hostname.Value {
+				continue
+			}
+
+			if !plan.EnrolmentKeyId.Null && system.EnrolmentKeyId != enclaveEnrolmentKey.EnrolmentKeyId(plan.EnrolmentKeyId.Value) {
+				continue
+			}
+
+			matches = append(matches, system)
+		}
+
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+
+		if len(matches) > 1 {
+			return enrolledSystem{}, fmt.Errorf("found %d systems waiting for approval that match, set both hostname and enrolment_key_id to pick one", len(matches))
+		}
+
+		select {
+		case <-pollCtx.Done():
+			// the wait lasts as long as the create timeout when there is one, so ctx can run out at the same time
+			if errors.Is(pollCtx.Err(), context.DeadlineExceeded) {
+				return enrolledSystem{}, timedOut
+			}
+			return enrolledSystem{}, pollCtx.Err()
+		case <-time.After(systemApprovalPollInterval):
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package enclave
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestSystemApprovalResource(t *testing.T) {
+	pollInterval := systemApprovalPollInterval
+	systemApprovalPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { systemApprovalPollInterval = pollInterval })
+
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_system_approval",
+		steps: []resourceTestStep{
+			{
+				preConfig: func(api *mockApi) {
+					// the system enrols while the approval is waiting for it
+					go func() {
+						time.Sleep(50 * time.Millisecond)
+
+						api.mu.Lock()
+						defer api.mu.Unlock()
+
+						api.unapprovedSystems["AAAAA"] = &enrolledSystem{SystemId: "AAAAA", Hostname: "vm-01", EnrolmentKeyId: 1}
+						api.unapprovedSystems["BBBBB"] = &enrolledSystem{SystemId: "BBBBB", Hostname: "vm-02", EnrolmentKeyId: 1}
+					}()
+				},
+				config: map[string]any{
+					"hostname":         "vm-01",
+					"enrolment_key_id": 1,
+					"timeouts":         []any{map[string]any{"create": "5s"}},
+				},
+				expect: map[string]any{
+					"id": "AAAAA",
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if _, ok := api.systems["AAAAA"]; !ok {
+						t.Errorf("expected system AAAAA to be approved")
+					}
+					if _, ok := api.unapprovedSystems["BBBBB"]; !ok {
+						t.Errorf("expected system BBBBB to still be waiting for approval")
+					}
+				},
+			},
+			{
+				// changing the timeout doesn't approve another system
+				config: map[string]any{
+					"hostname":         "vm-01",
+					"enrolment_key_id": 1,
+					"timeouts":         []any{map[string]any{"create": "1m"}},
+				},
+				expect: map[string]any{
+					"id": "AAAAA",
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					// revoking the system means a new one has to be waited for
+					delete(api.systems, "AAAAA")
+				},
+				config: map[string]any{
+					"enrolment_key_id": 1,
+					"timeouts":         []any{map[string]any{"create": "1m"}},
+				},
+				expect: map[string]any{
+					"id": "BBBBB",
+				},
+			},
+		},
+	})
+}
+
+func TestSystemApprovalResourceErrors(t *testing.T) {
+	pollInterval := systemApprovalPollInterval
+	systemApprovalPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { systemApprovalPollInterval = pollInterval })
+
+	api := newMockApi(t)
+	api.unapprovedSystems["AAAAA"] = &enrolledSystem{SystemId: "AAAAA", Hostname: "vm-01", EnrolmentKeyId: 1}
+	api.unapprovedSystems["BBBBB"] = &enrolledSystem{SystemId: "BBBBB", Hostname: "vm-02", EnrolmentKeyId: 1}
+
+	p := newTestProvider(t, api, nil)
+	typ := p.resourceSchema("enclave_system_approval").ValueType()
+
+	errorCases := map[string]struct {
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"no lookup":        {map[string]any{}, regexp.MustCompile(`At least one of "hostname" or "enrolment_key_id"`)},
+		"bad timeout":      {map[string]any{"hostname": "vm-01", "timeout": "soon"}, regexp.MustCompile(`AttributeName\("timeout"\): Invalid duration: "soon" is not valid`)},
+		"zero timeout":     {map[string]any{"hostname": "vm-01", "timeout": "0s"}, regexp.MustCompile(`AttributeName\("timeout"\): Invalid duration: "0s" is not valid`)},
+		"negative timeout": {map[string]any{"hostname": "vm-01", "timeout": "-5m"}, regexp.MustCompile(`AttributeName\("timeout"\): Invalid duration: "-5m" is not valid`)},
+		"timed out": {
+			map[string]any{"hostname": "vm-03", "timeouts": []any{map[string]any{"create": "50ms"}}},
+			regexp.MustCompile(`no matching system was waiting for approval after 50ms`),
+		},
+		"timed out with the deprecated timeout": {
+			map[string]any{"hostname": "vm-03", "timeout": "50ms"},
+			regexp.MustCompile(`no matching system was waiting for approval after 50ms`),
+		},
+		"create timeout over the deprecated timeout": {
+			map[string]any{"hostname": "vm-03", "timeout": "1m", "timeouts": []any{map[string]any{"create": "50ms"}}},
+			regexp.MustCompile(`no matching system was waiting for approval after 50ms`),
+		},
+		"ambiguous": {map[string]any{"enrolment_key_id": 1}, regexp.MustCompile(`found 2 systems waiting for approval`)},
+	}
+
+	for name, errorCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.apply("enclave_system_approval", tftypes.NewValue(typ, nil), errorCase.config)
+			if err == nil || !errorCase.error.MatchString(err.Error()) {
+				t.Errorf("expected error matching %s, got %v", errorCase.error, err)
+			}
+		})
+	}
+}
+
+func TestSystemApprovalResourceTimeoutDuringRequest(t *testing.T) {
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+	typ := p.resourceSchema("enclave_system_approval").ValueType()
+
+	// the wait is given up on once the timeout passes, rather than waiting on the api
+	api.delay = 5 * time.Second
+	started := time.Now()
+	_, err := p.apply("enclave_system_approval", tftypes.NewValue(typ, nil), map[string]any{
+		"hostname": "vm-01",
+		"timeout":  "50ms",
+	})
+	if err == nil || !strings.Contains(err.Error(), "no matching system was waiting for approval after 50ms") {
+		t.Fatalf("expected the approval to time out, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the approval to stop after its timeout, took %s", elapsed)
+	}
+}
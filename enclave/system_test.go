@@ -0,0 +1,308 @@
+package enclave
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestSystemResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_system",
+		steps: []resourceTestStep{
+			{
+				preConfig: func(api *mockApi) {
+					api.systems["AAAAA"] = &enrolledSystem{
+						SystemId:       "AAAAA",
+						Hostname:       "web-01",
+						Description:    "web-01",
+						State:          enclaveSystem.Connected,
+						EnrolmentKeyId: 1,
+						IsEnabled:      true,
+						VirtualAddress: "100.64.0.1",
+						Tags:           []enclaveTag.TagReference{{Tag: "web", Ref: "ref1"}},
+					}
+				},
+				config: map[string]any{
+					"id": "AAAAA",
+				},
+				expect: map[string]any{
+					"hostname":         "web-01",
+					"enrolment_key_id": 1,
+					"description":      "web-01",
+					"tags":             []string{"web"},
+					"is_enabled":       true,
+					"is_approved":      true,
+					"virtual_address":  "100.64.0.1",
+				},
+			},
+			{
+				config: map[string]any{
+					"id":          "AAAAA",
+					"description": "Web Server",
+					"notes":       "in the rack",
+					"tags":        []string{"web", "server"},
+					"is_enabled":  false,
+				},
+				expect: map[string]any{
+					"description": "Web Server",
+					"notes":       "in the rack",
+					"tags":        []string{"web", "server"},
+					"is_enabled":  false,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if system := api.systems["AAAAA"]; system.IsEnabled || system.Notes != "in the rack" {
+						t.Errorf("expected the system to be disabled with notes, got %#v", system)
+					}
+				},
+			},
+			{
+				// notes can be cleared unlike the go-enclaveapi patches
+				config: map[string]any{
+					"id":          "AAAAA",
+					"description": "Web Server",
+					"notes":       "",
+					"tags":        []string{},
+					"is_enabled":  true,
+				},
+				expect: map[string]any{
+					"notes":      "",
+					"tags":       []string{},
+					"is_enabled": true,
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					delete(api.systems, "AAAAA")
+				},
+				config: map[string]any{
+					"id": "AAAAA",
+				},
+				expectError: regexp.MustCompile(`System not found`),
+			},
+		},
+	})
+}
+
+func TestSystemResourceByHostname(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_system",
+		steps: []resourceTestStep{
+			{
+				preConfig: func(api *mockApi) {
+					api.unapprovedSystems["BBBBB"] = &enrolledSystem{
+						SystemId:       "BBBBB",
+						Hostname:       "build-01",
+						EnrolmentKeyId: 2,
+					}
+					// the same hostname enrolled with a different key
+					api.systems["CCCCC"] = &enrolledSystem{
+						SystemId:       "CCCCC",
+						Hostname:       "build-01",
+						EnrolmentKeyId: 3,
+						IsEnabled:      true,
+					}
+				},
+				config: map[string]any{
+					"hostname":         "build-01",
+					"enrolment_key_id": 2,
+					"description":      "Build Agent",
+				},
+				expect: map[string]any{
+					"id":              "BBBBB",
+					"description":     "Build Agent",
+					"is_approved":     false,
+					"virtual_address": nil,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if system := api.unapprovedSystems["BBBBB"]; system == nil || system.Description != "Build Agent" {
+						t.Errorf("expected the pending system to be updated, got %#v", system)
+					}
+				},
+			},
+			{
+				config: map[string]any{
+					"hostname":         "build-01",
+					"enrolment_key_id": 2,
+					"description":      "Build Agent",
+					"is_approved":      true,
+				},
+				expect: map[string]any{
+					"is_approved": true,
+					"is_enabled":  true,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if _, ok := api.systems["BBBBB"]; !ok {
+						t.Errorf("expected system BBBBB to be approved")
+					}
+					if state["virtual_address"] == nil {
+						t.Errorf("expected a virtual address once approved")
+					}
+				},
+			},
+			{
+				config: map[string]any{
+					"hostname":         "build-01",
+					"enrolment_key_id": 2,
+					"description":      "Build Agent",
+					"is_approved":      false,
+				},
+				expectError: regexp.MustCompile(`can't be unapproved`),
+			},
+			{
+				preConfig: func(api *mockApi) {
+					// the machine is renamed, which mustn't replace and so revoke the system
+					api.systems["BBBBB"].Hostname = "build-02"
+				},
+				config: map[string]any{
+					"hostname":         "build-01",
+					"enrolment_key_id": 2,
+					"description":      "Build Agent",
+				},
+				expectError: regexp.MustCompile(`System BBBBB has the hostname "build-02"`),
+			},
+			{
+				config: map[string]any{
+					"hostname":         "build-02",
+					"enrolment_key_id": 2,
+					"description":      "Build Agent",
+				},
+				expect: map[string]any{
+					"id":       "BBBBB",
+					"hostname": "build-02",
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					if _, ok := api.systems["BBBBB"]; !ok {
+						t.Errorf("expected system BBBBB to still be enrolled")
+					}
+				},
+			},
+			{
+				config: map[string]any{
+					"hostname":         "build-02",
+					"enrolment_key_id": 3,
+					"description":      "Build Agent",
+				},
+				expectError: regexp.MustCompile(`System BBBBB was enrolled with key 2`),
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+		checkDestroy: func(api *mockApi) error {
+			if _, ok := api.systems["BBBBB"]; ok {
+				return fmt.Errorf("system BBBBB has not been revoked")
+			}
+			if _, ok := api.systems["CCCCC"]; !ok {
+				return fmt.Errorf("system CCCCC should not have been revoked")
+			}
+			return nil
+		},
+	})
+}
+
+func TestSystemResourceValidation(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+	typ := p.resourceSchema("enclave_system").ValueType()
+
+	errorCases := map[string]map[string]any{
+		"neither":            {"description": "no lookup"},
+		"both":               {"id": "AAAAA", "hostname": "web-01", "enrolment_key_id": 1},
+		"hostname only":      {"hostname": "web-01"},
+		"enrolment key only": {"enrolment_key_id": 1},
+	}
+
+	for name, config := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.apply("enclave_system", tftypes.NewValue(typ, nil), config)
+			if err == nil || !regexp.MustCompile(`Invalid system lookup`).MatchString(err.Error()) {
+				t.Errorf("expected an invalid lookup error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSystemResourceTagOrder(t *testing.T) {
+	api := newMockApi(t)
+	p := newTestProvider(t, api, nil)
+
+	api.systems["AAAAA"] = &enrolledSystem{
+		SystemId:       "AAAAA",
+		Hostname:       "web-01",
+		State:          enclaveSystem.Connected,
+		EnrolmentKeyId: 1,
+		IsEnabled:      true,
+	}
+
+	config := map[string]any{
+		"id":   "AAAAA",
+		"tags": []string{"web", "server"},
+	}
+
+	typ := p.resourceSchema("enclave_system").ValueType()
+	state, err := p.apply("enclave_system", tftypes.NewValue(typ, nil), config)
+	if err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+
+	// the api returning tags in a different order isn't a change
+	tags := api.systems["AAAAA"].Tags
+	tags[0], tags[1] = tags[1], tags[0]
+
+	refreshed, err := p.refresh("enclave_system", state)
+	if err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+
+	configValue, _ := p.resourceConfig("enclave_system", config)
+	planned, _, err := p.plan("enclave_system", refreshed, configValue)
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if !planned.Equal(refreshed) {
+		t.Errorf("expected an empty plan\nstate: %s\nplan:  %s", refreshed, planned)
+	}
+}
+
+func TestSystemResourceUpgradeState(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	upgraded, err := p.upgradeState("enclave_system", 0, `{
+		"id": "AAAAA",
+		"hostname": "web-01",
+		"enrolment_key_id": 1,
+		"description": "web-01",
+		"notes": null,
+		"tags": ["web", "server", "web"],
+		"is_enabled": true,
+		"is_approved": true,
+		"virtual_address": "100.64.0.1",
+		"organisation_id": "org-1",
+		"timeouts": []
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"id":               "AAAAA",
+		"hostname":         "web-01",
+		"enrolment_key_id": int64(1),
+		"description":      "web-01",
+		"notes":            nil,
+		"tags":             []any{"server", "web"},
+		"is_enabled":       true,
+		"is_approved":      true,
+		"virtual_address":  "100.64.0.1",
+		"organisation_id":  "org-1",
+		"timeouts":         []any{},
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("expected %#v, got %#v", expected, upgraded)
+	}
+}
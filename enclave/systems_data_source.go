@@ -0,0 +1,182 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+type systemsDataSourceType struct{}
+
+func (s systemsDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Attributes: map[string]tfsdk.Attribute{
+			"tag": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"enrolment_key_id": {
+				Type:     types.Int64Type,
+				Optional: true,
+			},
+			"hostname_pattern": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"systems": {
+				Attributes: tfsdk.ListNestedAttributes(map[string]tfsdk.Attribute{
+					"id": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"hostname": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"description": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"tags": {
+						Type: types.SetType{
+							ElemType: types.StringType,
+						},
+						Computed: true,
+					},
+					"enrolment_key_id": {
+						Type:     types.Int64Type,
+						Computed: true,
+					},
+					"virtual_address": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"platform": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"state": {
+						Type:     types.StringType,
+						Computed: true,
+					},
+					"is_enabled": {
+						Type:     types.BoolType,
+						Computed: true,
+					},
+				}),
+				Computed: true,
+			},
+		},
+	}, nil
+}
+
+func (s systemsDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return systemsDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type systemsDataSource struct {
+	provider provider
+}
+
+// ValidateConfig implements tfsdk.DataSourceWithValidateConfig
+func (s systemsDataSource) ValidateConfig(ctx context.Context, req tfsdk.ValidateDataSourceConfigRequest, resp *tfsdk.ValidateDataSourceConfigResponse) {
+	var config SystemsState
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.HostnamePattern.Null || config.HostnamePattern.Unknown {
+		return
+	}
+
+	if _, err := path.Match(config.HostnamePattern.Value, ""); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			tftypes.NewAttributePath().WithAttributeName("hostname_pattern"),
+			"Invalid hostname pattern",
+			fmt.Sprintf("%q is not a valid pattern: %s", config.HostnamePattern.Value, err),
+		)
+	}
+}
+
+// Read implements tfsdk.DataSource
+func (s systemsDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !s.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	var state SystemsState
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var enrolmentKeyId *enclaveEnrolmentKey.EnrolmentKeyId
+	if !state.EnrolmentKeyId.Null {
+		id := enclaveEnrolmentKey.EnrolmentKeyId(state.EnrolmentKeyId.Value)
+		enrolmentKeyId = &id
+	}
+
+	systems, err := listSystems(s.provider.withContext(ctx), enrolmentKeyId)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading systems", "Could not list systems", err)
+		return
+	}
+
+	state.Systems = []SystemDataSourceState{}
+	for _, system := range systems {
+		if !state.Tag.Null && !hasTag(system.Tags, state.Tag.Value) {
+			continue
+		}
+
+		// the pattern was checked in ValidateConfig so there's no error to handle
+		if matched, _ := path.Match(state.HostnamePattern.Value, system.Hostname); !state.HostnamePattern.Null && !matched {
+			continue
+		}
+
+		state.Systems = append(state.Systems, SystemDataSourceState{
+			Id:             types.String{Value: string(system.SystemId)},
+			Hostname:       types.String{Value: system.Hostname},
+			Description:    stringValueOrNull(system.Description),
+			Tags:           fromTagReferences(system.Tags, nil),
+			EnrolmentKeyId: types.Int64{Value: int64(system.EnrolmentKeyId)},
+			VirtualAddress: stringValueOrNull(system.VirtualAddress),
+			Platform:       stringValueOrNull(system.PlatformType),
+			State:          types.String{Value: string(system.State)},
+			IsEnabled:      types.Bool{Value: system.IsEnabled},
+		})
+	}
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Get every enrolled system in the organisation, optionally only those enrolled with a key
+func listSystems(p provider, enrolmentKeyId *enclaveEnrolmentKey.EnrolmentKeyId) ([]enrolledSystem, error) {
+	query := url.Values{}
+	query.Set("include_disabled", "true")
+	if enrolmentKeyId != nil {
+		query.Set("enrolment_key", fmt.Sprint(*enrolmentKeyId))
+	}
+
+	return listAll[enrolledSystem](p.api, "/systems", query)
+}
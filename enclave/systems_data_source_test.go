@@ -0,0 +1,104 @@
+package enclave
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclaveSystem "github.com/enclave-networks/go-enclaveapi/data/enrolledsystem"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+)
+
+func TestSystemsDataSource(t *testing.T) {
+	api := newMockApi(t)
+
+	api.systems["AAAAA"] = &enrolledSystem{
+		SystemId:       "AAAAA",
+		Hostname:       "web-01",
+		Description:    "first web server",
+		State:          enclaveSystem.Connected,
+		EnrolmentKeyId: 1,
+		IsEnabled:      true,
+		PlatformType:   "Linux",
+		VirtualAddress: "100.64.0.1",
+		Tags:           []enclaveTag.TagReference{{Tag: "web", Ref: "ref1"}},
+	}
+	api.systems["BBBBB"] = &enrolledSystem{
+		SystemId:       "BBBBB",
+		Hostname:       "web-02",
+		State:          enclaveSystem.Disconnected,
+		EnrolmentKeyId: 2,
+		IsEnabled:      true,
+		PlatformType:   "Linux",
+		VirtualAddress: "100.64.0.2",
+		Tags:           []enclaveTag.TagReference{{Tag: "web", Ref: "ref1"}},
+	}
+	api.systems["CCCCC"] = &enrolledSystem{
+		SystemId:       "CCCCC",
+		Hostname:       "db-01",
+		State:          enclaveSystem.Disabled,
+		EnrolmentKeyId: 1,
+		IsEnabled:      false,
+		PlatformType:   "Windows",
+		VirtualAddress: "100.64.0.3",
+	}
+
+	p := newTestProvider(t, api, nil)
+
+	testCases := map[string]struct {
+		config   map[string]any
+		expected []any
+	}{
+		"all":              {map[string]any{}, []any{"AAAAA", "BBBBB", "CCCCC"}},
+		"tag":              {map[string]any{"tag": "web"}, []any{"AAAAA", "BBBBB"}},
+		"enrolment key":    {map[string]any{"enrolment_key_id": 1}, []any{"AAAAA", "CCCCC"}},
+		"hostname pattern": {map[string]any{"hostname_pattern": "web-*"}, []any{"AAAAA", "BBBBB"}},
+		"combined":         {map[string]any{"tag": "web", "enrolment_key_id": 1, "hostname_pattern": "web-0?"}, []any{"AAAAA"}},
+		"none":             {map[string]any{"hostname_pattern": "mail-*"}, []any{}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := p.readDataSource("enclave_systems", testCase.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []any{}
+			for _, system := range result["systems"].([]any) {
+				ids = append(ids, system.(map[string]any)["id"])
+			}
+
+			if !reflect.DeepEqual(testCase.expected, ids) {
+				t.Errorf("expected systems %v, got %v", testCase.expected, ids)
+			}
+		})
+	}
+
+	result, err := p.readDataSource("enclave_systems", map[string]any{"hostname_pattern": "web-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []any{
+		map[string]any{
+			"id":               "AAAAA",
+			"hostname":         "web-01",
+			"description":      "first web server",
+			"tags":             []any{"web"},
+			"enrolment_key_id": int64(1),
+			"virtual_address":  "100.64.0.1",
+			"platform":         "Linux",
+			"state":            "Connected",
+			"is_enabled":       true,
+		},
+	}
+	if !reflect.DeepEqual(expected, result["systems"]) {
+		t.Errorf("expected %#v, got %#v", expected, result["systems"])
+	}
+
+	_, err = p.readDataSource("enclave_systems", map[string]any{"hostname_pattern": "web-["})
+	if err == nil || !regexp.MustCompile(`Invalid hostname pattern`).MatchString(err.Error()) {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+}
@@ -2,18 +2,20 @@ package enclave
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 
 	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 type tagResourceType struct{}
 
 func (t tagResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 1,
 		Attributes: map[string]tfsdk.Attribute{
 			"ref": {
 				Type:     types.StringType,
@@ -32,11 +34,15 @@ func (t tagResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagno
 				Optional: true,
 			},
 			"trust_requirements": {
-				Type: types.ListType{
+				Type: types.SetType{
 					ElemType: types.Int64Type,
 				},
 				Optional: true,
 			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -69,6 +75,17 @@ func (t tag) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
 	tagCreate := enclaveTag.TagCreate{
 		Tag:               plan.Name.Value,
 		Colour:            plan.Colour.Value,
@@ -77,12 +94,10 @@ func (t tag) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *
 	}
 
 	// create request
-	tagResponse, err := t.provider.client.Tags.Create(tagCreate)
+	var tagResponse enclaveTag.DetailedTag
+	err := org.api.do(http.MethodPost, "/tags", nil, tagCreate, &tagResponse)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating Tag in enclave",
-			err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating Tag in enclave", "Could not create tag", err)
 		return
 	}
 
@@ -103,16 +118,31 @@ func (t tag) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsd
 		return
 	}
 
-	currentTag, err := t.provider.client.Tags.Get(state.Ref.Value)
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
+
+	var currentTag enclaveTag.DetailedTag
+	err := org.api.get("/tags/"+state.Ref.Value, nil, &currentTag)
+	if isNotFound(err) {
+		// the tag has been deleted outside of terraform, remove it so it gets recreated
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error reading tag ref",
-			"Could not read Id "+state.Ref.Value+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading tag ref", "Could not read Id "+state.Ref.Value, err)
 		return
 	}
 
-	setTagRef(currentTag, &state)
+	setTagState(currentTag, &state)
 	diags = resp.State.Set(ctx, state)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
@@ -134,18 +164,27 @@ func (t tag) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *
 	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 
-	updatedTag, err := t.provider.client.Tags.Update(state.Ref.Value, enclaveTag.TagPatch{
-		Tag:               plan.Name.Value,
-		Colour:            plan.Colour.Value,
-		Notes:             plan.Notes.Value,
-		TrustRequirements: toTrustRequirementIdArray(plan.TrustRequirements),
-	})
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
+	var updatedTag enclaveTag.DetailedTag
+	err := org.api.do(http.MethodPatch, "/tags/"+url.PathEscape(state.Ref.Value), nil, tagPatch{
+		Tag:               &plan.Name.Value,
+		Colour:            &plan.Colour.Value,
+		Notes:             &plan.Notes.Value,
+		TrustRequirements: patchList(toTrustRequirementIdArray(plan.TrustRequirements)),
+	}, &updatedTag)
 
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error updating Tag",
-			"Could not read Id "+state.Ref.Value+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating Tag", "Could not update Id "+state.Ref.Value, err)
 		return
 	}
 
@@ -167,13 +206,20 @@ func (t tag) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	//call api to delete
-	_, err := t.provider.client.Tags.Delete(state.Ref.Value)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting Tag",
-			"Could not read Id "+state.Ref.Value+": "+err.Error(),
-		)
+	err := org.api.do(http.MethodDelete, "/tags/"+state.Ref.Value, nil, nil, nil)
+	// already deleted outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error deleting Tag", "Could not delete Id "+state.Ref.Value, err)
 		return
 	}
 
@@ -181,12 +227,97 @@ func (t tag) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *
 	resp.State.RemoveResource(ctx)
 }
 
-// Import resource
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (t tag) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, t.provider, req, resp)
+}
+
+// ImportState implements tfsdk.ResourceWithImportState. Tags can be imported by their ref or their name,
+// so the tag is looked up straight away to save its ref, and the rest of it, in state.
 func (t tag) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
-	// Save the import identifier in the id attribute
-	tfsdk.ResourceImportStatePassthroughID(ctx, tftypes.NewAttributePath().WithAttributeName("id"), req, resp)
+	if !t.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before import, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	orgId, refOrName := splitImportId(req.ID)
+	if refOrName == "" {
+		resp.Diagnostics.AddError(
+			"Invalid import id",
+			"Expected a tag ref or name, optionally prefixed with an organisation id and a slash, got \""+req.ID+"\"",
+		)
+		return
+	}
+
+	org, diags := t.provider.forOrganisation(ctx, orgId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var importedTag enclaveTag.DetailedTag
+	err := org.api.get("/tags/"+refOrName, nil, &importedTag)
+	if isNotFound(err) {
+		resp.Diagnostics.AddError(
+			"Cannot import non-existent tag",
+			"No tag has the ref or name \""+refOrName+"\" in organisation "+org.organisationId().Value,
+		)
+		return
+	}
+
+	if err != nil {
+		addApiError(&resp.Diagnostics, resp.State.Schema, "Error importing Tag", "Could not read tag "+refOrName, err)
+		return
+	}
+
+	state := TagState{
+		OrganisationId: org.organisationId(),
+	}
+	setTagState(importedTag, &state)
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (t tag) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		// version 0 had a list of trust requirements, which is now a set so their order doesn't matter
+		stateUpgrade{
+			priorSchema: tfsdk.Schema{
+				Attributes: map[string]tfsdk.Attribute{
+					"ref":    {Type: types.StringType, Computed: true},
+					"name":   {Type: types.StringType, Required: true},
+					"colour": {Type: types.StringType, Optional: true},
+					"notes":  {Type: types.StringType, Optional: true},
+					"trust_requirements": {
+						Type:     types.ListType{ElemType: types.Int64Type},
+						Optional: true,
+					},
+					"organisation_id": {Type: types.StringType, Optional: true, Computed: true},
+				},
+				Blocks: map[string]tfsdk.Block{
+					"timeouts": timeoutsBlock(),
+				},
+			},
+			upgrade: listsToSets("trust_requirements"),
+		},
+	)
 }
 
 func setTagRef(tag enclaveTag.DetailedTag, state *TagState) {
 	state.Ref = types.String{Value: string(tag.Ref)}
 }
+
+// Map the full api tag back into state so out-of-band changes are detected
+func setTagState(tag enclaveTag.DetailedTag, state *TagState) {
+	setTagRef(tag, state)
+	state.Name = types.String{Value: tag.Tag}
+	state.Colour = stringValueOrNull(tag.Colour)
+	state.Notes = stringValueOrNull(tag.Notes)
+	state.TrustRequirements = fromUsedTrustRequirements(tag.TrustRequirements, state.TrustRequirements)
+}
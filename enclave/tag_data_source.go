@@ -0,0 +1,173 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+type tagDataSourceType struct{}
+
+func (t tagDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	attributes := tagDataSourceAttributes()
+
+	// a tag is looked up by one of these
+	attributes["ref"] = tfsdk.Attribute{
+		Type:     types.StringType,
+		Optional: true,
+		Computed: true,
+	}
+	attributes["name"] = tfsdk.Attribute{
+		Type:     types.StringType,
+		Optional: true,
+		Computed: true,
+	}
+
+	return tfsdk.Schema{
+		Attributes: attributes,
+	}, nil
+}
+
+// The attributes of a tag as read by the tag data sources, all of them computed
+func tagDataSourceAttributes() map[string]tfsdk.Attribute {
+	return map[string]tfsdk.Attribute{
+		"ref": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"name": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"colour": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"notes": {
+			Type:     types.StringType,
+			Computed: true,
+		},
+		"trust_requirements": {
+			Type: types.SetType{
+				ElemType: types.Int64Type,
+			},
+			Computed: true,
+		},
+		"system_count": {
+			Type:     types.Int64Type,
+			Computed: true,
+		},
+		"enrolment_key_count": {
+			Type:     types.Int64Type,
+			Computed: true,
+		},
+		"policy_count": {
+			Type:     types.Int64Type,
+			Computed: true,
+		},
+		"dns_record_count": {
+			Type:     types.Int64Type,
+			Computed: true,
+		},
+	}
+}
+
+func (t tagDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return tagDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type tagDataSource struct {
+	provider provider
+}
+
+// Read implements tfsdk.DataSource
+func (t tagDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !t.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	var config TagDataSourceState
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.Ref.Null == config.Name.Null {
+		resp.Diagnostics.AddError(
+			"Invalid tag lookup",
+			"Exactly one of \"ref\" or \"name\" must be set to find a tag",
+		)
+		return
+	}
+
+	// the api looks tags up by either their name or ref
+	attributeName := "ref"
+	nameOrRef := config.Ref.Value
+	if config.Ref.Null {
+		attributeName = "name"
+		nameOrRef = config.Name.Value
+	}
+
+	org := t.provider.withContext(ctx)
+
+	var tag enclaveTag.DetailedTag
+	err := org.api.get("/tags/"+nameOrRef, nil, &tag)
+	if isNotFound(err) {
+		resp.Diagnostics.AddAttributeError(
+			tftypes.NewAttributePath().WithAttributeName(attributeName),
+			"Could not find tag",
+			fmt.Sprintf("No tag has the %s %q in organisation %s", attributeName, nameOrRef, org.organisationId().Value),
+		)
+		return
+	}
+
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading tag", "Could not read tag "+nameOrRef, err)
+		return
+	}
+
+	var state TagDataSourceState
+	setTagDataSourceState(tag, &state)
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+func setTagDataSourceState(tag enclaveTag.DetailedTag, state *TagDataSourceState) {
+	state.Ref = types.String{Value: string(tag.Ref)}
+	state.Name = types.String{Value: tag.Tag}
+	state.Colour = stringValueOrNull(tag.Colour)
+	state.Notes = stringValueOrNull(tag.Notes)
+	state.TrustRequirements = fromUsedTrustRequirements(tag.TrustRequirements, nil)
+	state.SystemCount = types.Int64{Value: int64(tag.Systems)}
+	state.EnrolmentKeyCount = types.Int64{Value: int64(tag.Keys)}
+	state.PolicyCount = types.Int64{Value: int64(tag.Policies)}
+	state.DnsRecordCount = types.Int64{Value: int64(tag.DnsRecords)}
+}
+
+// Get every tag in the organisation, optionally filtered by a search term
+func listTags(p provider, searchTerm *string) ([]enclaveTag.BasicTag, error) {
+	query := url.Values{}
+	if searchTerm != nil {
+		query.Set("search", *searchTerm)
+	}
+
+	return listAll[enclaveTag.BasicTag](p.api, "/tags", query)
+}
@@ -0,0 +1,81 @@
+package enclave
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclaveEnrolmentKey "github.com/enclave-networks/go-enclaveapi/data/enrolmentkey"
+	enclavePolicy "github.com/enclave-networks/go-enclaveapi/data/policy"
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
+)
+
+func TestTagDataSource(t *testing.T) {
+	api := newMockApi(t)
+
+	api.tags["ref1"] = &enclaveTag.DetailedTag{
+		Tag:               "dev",
+		Ref:               "ref1",
+		Colour:            "#fcba03",
+		Notes:             "developer laptops",
+		TrustRequirements: []enclaveTrustRequirement.UsedTrustRequirement{{Id: 7}},
+	}
+	api.policies[1] = &enclavePolicy.Policy{
+		Id:         1,
+		SenderTags: []enclaveTag.TagReference{{Tag: "dev", Ref: "ref1"}},
+	}
+	api.enrolmentKeys[2] = &enclaveEnrolmentKey.EnrolmentKey{
+		Id:   2,
+		Tags: []enclaveTag.TagReference{{Tag: "dev", Ref: "ref1"}},
+	}
+
+	p := newTestProvider(t, api, nil)
+
+	expected := map[string]any{
+		"ref":                 "ref1",
+		"name":                "dev",
+		"colour":              "#fcba03",
+		"notes":               "developer laptops",
+		"trust_requirements":  []any{int64(7)},
+		"system_count":        int64(0),
+		"enrolment_key_count": int64(1),
+		"policy_count":        int64(1),
+		"dns_record_count":    int64(0),
+	}
+
+	byRef, err := p.readDataSource("enclave_tag", map[string]any{"ref": "ref1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, byRef) {
+		t.Errorf("expected %#v, got %#v", expected, byRef)
+	}
+
+	byName, err := p.readDataSource("enclave_tag", map[string]any{"name": "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, byName) {
+		t.Errorf("expected %#v, got %#v", expected, byName)
+	}
+
+	errorCases := map[string]struct {
+		config map[string]any
+		error  *regexp.Regexp
+	}{
+		"neither":      {map[string]any{}, regexp.MustCompile(`Exactly one of "ref" or "name"`)},
+		"both":         {map[string]any{"ref": "ref1", "name": "dev"}, regexp.MustCompile(`Exactly one of "ref" or "name"`)},
+		"missing name": {map[string]any{"name": "nobody"}, regexp.MustCompile(`AttributeName\("name"\): Could not find tag: No tag has the name "nobody" in organisation mock-org`)},
+		"missing ref":  {map[string]any{"ref": "ref9"}, regexp.MustCompile(`AttributeName\("ref"\): Could not find tag: No tag has the ref "ref9" in organisation mock-org`)},
+	}
+
+	for name, errorCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.readDataSource("enclave_tag", errorCase.config)
+			if err == nil || !errorCase.error.MatchString(err.Error()) {
+				t.Errorf("expected error matching %s, got %v", errorCase.error, err)
+			}
+		})
+	}
+}
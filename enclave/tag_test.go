@@ -0,0 +1,159 @@
+package enclave
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
+)
+
+func TestTagResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_tag",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"name": "database",
+				},
+				expect: map[string]any{
+					"name":   "database",
+					"colour": nil,
+					"notes":  nil,
+				},
+			},
+			{
+				config: map[string]any{
+					"name":   "databases",
+					"colour": "#fcba03",
+					"notes":  "all the databases",
+				},
+				expect: map[string]any{
+					"name":   "databases",
+					"colour": "#fcba03",
+					"notes":  "all the databases",
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					for _, tag := range api.tags {
+						tag.Colour = "#000000"
+					}
+				},
+				config: map[string]any{
+					"name":   "databases",
+					"colour": "#fcba03",
+					"notes":  "all the databases",
+				},
+				expect: map[string]any{
+					"colour": "#fcba03",
+				},
+			},
+			{
+				// removed from config, and notes added in the portal, are cleared
+				preConfig: func(api *mockApi) {
+					for _, tag := range api.tags {
+						tag.Notes = "added in the portal"
+						tag.TrustRequirements = api.usedTrustRequirements([]enclaveTrustRequirement.TrustRequirementId{5})
+					}
+				},
+				config: map[string]any{
+					"name": "databases",
+				},
+				expect: map[string]any{
+					"colour":             nil,
+					"notes":              nil,
+					"trust_requirements": nil,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					for _, tag := range api.tags {
+						if tag.Colour != "" || tag.Notes != "" || len(tag.TrustRequirements) != 0 {
+							t.Errorf("expected colour, notes and trust requirements to be cleared: %#v", tag)
+						}
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return mockOrgId + "/" + state["ref"].(string)
+		},
+		checkDestroy: func(api *mockApi) error {
+			if len(api.tags) != 0 {
+				return fmt.Errorf("%d tags still exist", len(api.tags))
+			}
+			return nil
+		},
+	})
+}
+
+func TestTagResourceImportByName(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_tag",
+		steps: []resourceTestStep{
+			{
+				preConfig: func(api *mockApi) {
+					api.trustRequirements[5] = &enclaveTrustRequirement.TrustRequirement{
+						Id:          5,
+						Description: "portal login",
+						Type:        enclaveTrustRequirement.UserAuthentication,
+					}
+				},
+				config: map[string]any{
+					"name":               "databases",
+					"colour":             "#fcba03",
+					"notes":              "all the databases",
+					"trust_requirements": []int{5},
+				},
+			},
+		},
+		// the imported state has to match what was created, ref and all
+		importStateId: func(state map[string]any) string {
+			return "databases"
+		},
+	})
+}
+
+func TestTagResourceImportNotFound(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	_, err := p.importState("enclave_tag", "databases")
+	if err == nil || !regexp.MustCompile(`No tag has the ref or name "databases"`).MatchString(err.Error()) {
+		t.Errorf("expected an error saying the tag doesn't exist, got: %v", err)
+	}
+
+	_, err = p.importState("enclave_tag", mockOrgId+"/")
+	if err == nil || !regexp.MustCompile(`Expected a tag ref or name`).MatchString(err.Error()) {
+		t.Errorf("expected an invalid import id error, got: %v", err)
+	}
+}
+
+func TestTagResourceUpgradeState(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	upgraded, err := p.upgradeState("enclave_tag", 0, `{
+		"ref": "tag-1",
+		"name": "servers",
+		"colour": "#FF0000",
+		"notes": null,
+		"trust_requirements": [2, 1, 2],
+		"organisation_id": "org-1",
+		"timeouts": []
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"ref":                "tag-1",
+		"name":               "servers",
+		"colour":             "#FF0000",
+		"notes":              nil,
+		"trust_requirements": []any{int64(1), int64(2)},
+		"organisation_id":    "org-1",
+		"timeouts":           []any{},
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("expected %#v, got %#v", expected, upgraded)
+	}
+}
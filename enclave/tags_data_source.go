@@ -0,0 +1,91 @@
+package enclave
+
+import (
+	"context"
+
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type tagsDataSourceType struct{}
+
+func (t tagsDataSourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Attributes: map[string]tfsdk.Attribute{
+			"search": {
+				Type:     types.StringType,
+				Optional: true,
+			},
+			"tags": {
+				Attributes: tfsdk.ListNestedAttributes(tagDataSourceAttributes()),
+				Computed:   true,
+			},
+		},
+	}, nil
+}
+
+func (t tagsDataSourceType) NewDataSource(_ context.Context, pr tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	return tagsDataSource{
+		provider: *(pr.(*provider)),
+	}, nil
+}
+
+type tagsDataSource struct {
+	provider provider
+}
+
+// Read implements tfsdk.DataSource
+func (t tagsDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	if !t.provider.configured {
+		resp.Diagnostics.AddError(
+			"Provider not configured",
+			"The provider hasn't been configured before reading, "+
+				"likely because it depends on an unknown value from another resource.",
+		)
+		return
+	}
+
+	var state TagsState
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var searchTerm *string
+	if !state.Search.Null {
+		searchTerm = &state.Search.Value
+	}
+
+	org := t.provider.withContext(ctx)
+
+	tags, err := listTags(org, searchTerm)
+	if err != nil {
+		addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading tags", "Could not list tags", err)
+		return
+	}
+
+	// the tag list leaves out notes and trust requirements so each tag has to be read in full
+	state.Tags = []TagDataSourceState{}
+	for _, item := range tags {
+		var tag enclaveTag.DetailedTag
+		err := org.api.get("/tags/"+string(item.Ref), nil, &tag)
+		if err != nil {
+			addApiError(&resp.Diagnostics, req.Config.Schema, "Error reading tag", "Could not read tag "+string(item.Ref), err)
+			return
+		}
+
+		var tagState TagDataSourceState
+		setTagDataSourceState(tag, &tagState)
+
+		state.Tags = append(state.Tags, tagState)
+	}
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
@@ -0,0 +1,56 @@
+package enclave
+
+import (
+	"reflect"
+	"testing"
+
+	enclaveTag "github.com/enclave-networks/go-enclaveapi/data/tag"
+)
+
+func TestTagsDataSource(t *testing.T) {
+	api := newMockApi(t)
+
+	api.tags["ref1"] = &enclaveTag.DetailedTag{Tag: "dev", Ref: "ref1", Notes: "developer laptops"}
+	api.tags["ref2"] = &enclaveTag.DetailedTag{Tag: "db", Ref: "ref2", Colour: "#ff0000"}
+	api.tags["ref3"] = &enclaveTag.DetailedTag{Tag: "devops", Ref: "ref3"}
+
+	p := newTestProvider(t, api, nil)
+
+	testCases := map[string]struct {
+		config   map[string]any
+		expected []any
+	}{
+		"all":    {map[string]any{}, []any{"dev", "db", "devops"}},
+		"search": {map[string]any{"search": "dev"}, []any{"dev", "devops"}},
+		"none":   {map[string]any{"search": "nobody"}, []any{}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := p.readDataSource("enclave_tags", testCase.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := []any{}
+			for _, tag := range result["tags"].([]any) {
+				names = append(names, tag.(map[string]any)["name"])
+			}
+
+			if !reflect.DeepEqual(testCase.expected, names) {
+				t.Errorf("expected tags %v, got %v", testCase.expected, names)
+			}
+		})
+	}
+
+	// notes aren't in the tag list so make sure each tag was read in full
+	result, err := p.readDataSource("enclave_tags", map[string]any{"search": "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := result["tags"].([]any)[0].(map[string]any)
+	if dev["notes"] != "developer laptops" {
+		t.Errorf("expected notes to be read, got %#v", dev)
+	}
+}
@@ -3,7 +3,7 @@ package enclave
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"net/http"
 	"strings"
 
 	enclaveTrustRequirement "github.com/enclave-networks/go-enclaveapi/data/trustrequirement"
@@ -11,12 +11,14 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 type trustRequirementResourceType struct{}
 
 func (t trustRequirementResourceType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		Version: 0,
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
 				Type:     types.Int64Type,
@@ -61,7 +63,19 @@ func (t trustRequirementResourceType) GetSchema(_ context.Context) (tfsdk.Schema
 					},
 				}),
 				Optional: true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					// the type of a trust requirement is which of its settings are set, and the api can't change it
+					tfsdk.RequiresReplaceIf(
+						trustRequirementTypeChanged,
+						"the trust requirement is replaced when its type changes",
+						"the trust requirement is replaced when its type changes",
+					),
+				},
 			},
+			"organisation_id": organisationIdAttribute(),
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -95,6 +109,17 @@ func (t trustRequirement) Create(ctx context.Context, req tfsdk.CreateResourceRe
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "create")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, plan.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
 	// Let's check lengths and add some warnings
 	validateTrustRequirement(plan, &resp.Diagnostics)
 
@@ -117,12 +142,10 @@ func (t trustRequirement) Create(ctx context.Context, req tfsdk.CreateResourceRe
 	}
 
 	// create request
-	trustRequirementResponse, err := t.provider.client.TrustRequirements.Create(trustRequirementCreate)
+	var trustRequirementResponse enclaveTrustRequirement.TrustRequirement
+	err = org.api.do(http.MethodPost, "/trust-requirements", nil, trustRequirementCreate, &trustRequirementResponse)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating Trust Requirement in enclave",
-			err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error creating Trust Requirement in enclave", "Could not create trust requirement", err)
 		return
 	}
 
@@ -145,15 +168,22 @@ func (t trustRequirement) Delete(ctx context.Context, req tfsdk.DeleteResourceRe
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "delete")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	trustRequirementId := enclaveTrustRequirement.TrustRequirementId(state.Id.Value)
 
 	//call api to delete
-	_, err := t.provider.client.TrustRequirements.Delete(trustRequirementId)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error deleting Trust Requirement",
-			"Could not read Id "+fmt.Sprint(trustRequirementId)+": "+err.Error(),
-		)
+	err := org.api.do(http.MethodDelete, fmt.Sprintf("/trust-requirements/%d", trustRequirementId), nil, nil, nil)
+	// already deleted outside of terraform is fine as that's what we wanted
+	if err != nil && !isNotFound(err) {
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error deleting Trust Requirement", "Could not delete Id "+fmt.Sprint(trustRequirementId), err)
 		return
 	}
 
@@ -170,18 +200,32 @@ func (t trustRequirement) Read(ctx context.Context, req tfsdk.ReadResourceReques
 		return
 	}
 
+	ctx, cancel := operationContext(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.OrganisationId = org.organisationId()
 	trustRequirementId := enclaveTrustRequirement.TrustRequirementId(state.Id.Value)
 
-	currentTrustRequirement, err := t.provider.client.TrustRequirements.Get(trustRequirementId)
+	var currentTrustRequirement enclaveTrustRequirement.TrustRequirement
+	err := org.api.get(fmt.Sprintf("/trust-requirements/%d", trustRequirementId), nil, &currentTrustRequirement)
+	if isNotFound(err) {
+		// the trust requirement has been deleted outside of terraform, remove it so it gets recreated
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error reading Trust requirement Id",
-			"Could not read Id "+fmt.Sprint(trustRequirementId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.State.Schema, "Error reading Trust requirement Id", "Could not read Id "+fmt.Sprint(trustRequirementId), err)
 		return
 	}
 
-	setTrustRequirementStateId(currentTrustRequirement, &state)
+	setTrustRequirementState(currentTrustRequirement, &state)
 	diags = resp.State.Set(ctx, state)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
@@ -204,6 +248,17 @@ func (t trustRequirement) Update(ctx context.Context, req tfsdk.UpdateResourceRe
 	diags = req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 
+	ctx, cancel := operationContext(ctx, plan.Timeouts, "update")
+	defer cancel()
+
+	org, diags := t.provider.forOrganisation(ctx, state.OrganisationId)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.OrganisationId = org.organisationId()
+
 	// Let's check lengths and add some warnings
 	validateTrustRequirement(plan, &resp.Diagnostics)
 
@@ -217,20 +272,18 @@ func (t trustRequirement) Update(ctx context.Context, req tfsdk.UpdateResourceRe
 		)
 	}
 
-	updateTrustRequirement, err := t.provider.client.TrustRequirements.Update(trustRequirementId, enclaveTrustRequirement.TrustRequirementPatch{
-		Description: plan.Description.Value,
-		Notes:       plan.Notes.Value,
-		Settings: enclaveTrustRequirement.TrustRequirementSettings{
+	var updateTrustRequirement enclaveTrustRequirement.TrustRequirement
+	err = org.api.do(http.MethodPatch, fmt.Sprintf("/trust-requirements/%d", trustRequirementId), nil, trustRequirementPatch{
+		Description: &plan.Description.Value,
+		Notes:       &plan.Notes.Value,
+		Settings: &enclaveTrustRequirement.TrustRequirementSettings{
 			Configuration: config,
 			Conditions:    conditions,
 		},
-	})
+	}, &updateTrustRequirement)
 
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error updating Trust Requirement",
-			"Could not read Id "+fmt.Sprint(trustRequirementId)+": "+err.Error(),
-		)
+		addApiError(&resp.Diagnostics, req.Plan.Schema, "Error updating Trust Requirement", "Could not update Id "+fmt.Sprint(trustRequirementId), err)
 		return
 	}
 
@@ -243,11 +296,85 @@ func (t trustRequirement) Update(ctx context.Context, req tfsdk.UpdateResourceRe
 	}
 }
 
+// ModifyPlan implements tfsdk.ResourceWithModifyPlan
+func (t trustRequirement) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	planOrganisationId(ctx, t.provider, req, resp)
+}
+
+// UpgradeState implements tfsdk.ResourceWithUpgradeState
+func (t trustRequirement) UpgradeState(context.Context) map[int64]tfsdk.ResourceStateUpgrader {
+	// still on the first version of its schema, so there's nothing to upgrade from yet
+	return stateUpgraders()
+}
+
+// ImportState implements tfsdk.ResourceWithImportState
+func (trustRequirement) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	importStateInt64Id(ctx, req, resp)
+}
+
+// Check if the settings for a type of trust requirement have been added or removed
+func trustRequirementTypeChanged(_ context.Context, state, config attr.Value, _ *tftypes.AttributePath) (bool, diag.Diagnostics) {
+	return state.IsNull() != config.IsNull(), nil
+}
+
 func setTrustRequirementStateId(trustRequirement enclaveTrustRequirement.TrustRequirement, state *TrustRequirementState) {
 	state.Id = types.Int64{Value: int64(trustRequirement.Id)}
 }
 
+// Map the full api trust requirement back into state so out-of-band changes are detected
+func setTrustRequirementState(trustRequirement enclaveTrustRequirement.TrustRequirement, state *TrustRequirementState) {
+	setTrustRequirementStateId(trustRequirement, state)
+	state.Description = types.String{Value: trustRequirement.Description}
+	state.Notes = stringValueOrNull(trustRequirement.Notes)
+
+	if trustRequirement.Type == enclaveTrustRequirement.UserAuthentication {
+		if state.UserAuthentication == nil {
+			state.UserAuthentication = &UserAuthenticationState{}
+		}
+
+		setUserAuthenticationState(trustRequirement.Settings, state.UserAuthentication)
+	}
+}
+
+// Reverse of getTrustRequirementSettings, turns the configuration and conditions maps back into a UserAuthenticationState
+func setUserAuthenticationState(settings enclaveTrustRequirement.TrustRequirementSettings, state *UserAuthenticationState) {
+	authority := settings.Configuration["authority"]
+	if !strings.EqualFold(state.Authority.Value, authority) {
+		state.Authority = types.String{Value: authority}
+	}
+
+	state.AzureTenantId = stringValueOrNull(settings.Configuration["tenantId"])
+	state.AzureGroupId = types.String{Null: true}
+
+	mfa := false
+	var customClaims []TrustRequirementCustomClaimsState
+	for _, condition := range settings.Conditions {
+		switch {
+		case condition["claim"] == "groups" && state.AzureGroupId.Null:
+			state.AzureGroupId = stringValueOrNull(condition["value"])
+		case condition["claim"] == "amr" && condition["value"] == "mfa":
+			mfa = true
+		default:
+			customClaims = append(customClaims, TrustRequirementCustomClaimsState{
+				Claim: types.String{Value: condition["claim"]},
+				Value: types.String{Value: condition["value"]},
+			})
+		}
+	}
+
+	// mfa is only sent when true so leave it unset unless it's been changed
+	if !state.Mfa.Null || mfa {
+		state.Mfa = types.Bool{Value: mfa}
+	}
+
+	state.CustomClaims = customClaims
+}
+
 func validateTrustRequirement(plan TrustRequirementState, diagnostics *diag.Diagnostics) {
+	if plan.UserAuthentication == nil {
+		return
+	}
+
 	if strings.ToLower(plan.UserAuthentication.Authority.Value) == string(Portal) && (!plan.UserAuthentication.AzureGroupId.Null || !plan.UserAuthentication.AzureTenantId.Null) {
 		diagnostics.AddWarning(
 			"Authetication Authority of Portal does not need any additional properties",
@@ -257,7 +384,7 @@ func validateTrustRequirement(plan TrustRequirementState, diagnostics *diag.Diag
 
 func getTrustRequirementSettings(plan TrustRequirementState) (trustRequirementType enclaveTrustRequirement.TrustRequirementType, config map[string]string, conditions []map[string]string, err error) {
 	// UserAuthentication has been set use that to create our maps
-	if !reflect.DeepEqual(plan.UserAuthentication, UserAuthenticationState{}) {
+	if plan.UserAuthentication != nil {
 		authorityLower := strings.ToLower(plan.UserAuthentication.Authority.Value)
 
 		if authorityLower == string(Portal) {
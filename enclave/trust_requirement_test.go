@@ -0,0 +1,174 @@
+package enclave
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestTrustRequirementResource(t *testing.T) {
+	runResourceTest(t, resourceTest{
+		resourceType: "enclave_trust_requirement",
+		steps: []resourceTestStep{
+			{
+				config: map[string]any{
+					"description": "portal login",
+					"user_authentication": map[string]any{
+						"authority": "portal",
+					},
+				},
+				expect: map[string]any{
+					"description": "portal login",
+					"notes":       nil,
+					"user_authentication": map[string]any{
+						"authority": "portal",
+					},
+				},
+			},
+			{
+				config: map[string]any{
+					"description": "azure login",
+					"notes":       "with mfa",
+					"user_authentication": map[string]any{
+						"authority":       "Azure",
+						"azure_tenant_id": "tenant",
+						"azure_group_id":  "group",
+						"mfa":             true,
+						"custom_claims": []any{
+							map[string]any{"claim": "department", "value": "engineering"},
+						},
+					},
+				},
+				expect: map[string]any{
+					"description": "azure login",
+					"notes":       "with mfa",
+					"user_authentication": map[string]any{
+						"authority":       "Azure",
+						"azure_tenant_id": "tenant",
+						"azure_group_id":  "group",
+						"mfa":             true,
+						"custom_claims": []any{
+							map[string]any{"claim": "department", "value": "engineering"},
+						},
+					},
+				},
+			},
+			{
+				preConfig: func(api *mockApi) {
+					for _, trustRequirement := range api.trustRequirements {
+						trustRequirement.Settings.Conditions = trustRequirement.Settings.Conditions[:1]
+					}
+				},
+				config: map[string]any{
+					"description": "azure login",
+					"notes":       "with mfa",
+					"user_authentication": map[string]any{
+						"authority":       "azure",
+						"azure_tenant_id": "tenant",
+						"azure_group_id":  "group",
+						"mfa":             true,
+						"custom_claims": []any{
+							map[string]any{"claim": "department", "value": "engineering"},
+						},
+					},
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					for _, trustRequirement := range api.trustRequirements {
+						if len(trustRequirement.Settings.Conditions) != 3 {
+							t.Errorf("expected the mfa and custom claim conditions to be restored, got %v", trustRequirement.Settings.Conditions)
+						}
+					}
+				},
+			},
+			{
+				// removing the notes clears them
+				config: map[string]any{
+					"description": "azure login",
+					"user_authentication": map[string]any{
+						"authority":       "azure",
+						"azure_tenant_id": "tenant",
+						"azure_group_id":  "group",
+						"mfa":             true,
+						"custom_claims": []any{
+							map[string]any{"claim": "department", "value": "engineering"},
+						},
+					},
+				},
+				expect: map[string]any{
+					"notes": nil,
+				},
+				check: func(t *testing.T, api *mockApi, state map[string]any) {
+					for _, trustRequirement := range api.trustRequirements {
+						if trustRequirement.Notes != "" {
+							t.Errorf("expected the notes to be cleared, got %q", trustRequirement.Notes)
+						}
+					}
+				},
+			},
+		},
+		importStateId: func(state map[string]any) string {
+			return fmt.Sprint(state["id"])
+		},
+		checkDestroy: func(api *mockApi) error {
+			if len(api.trustRequirements) != 0 {
+				return fmt.Errorf("%d trust requirements still exist", len(api.trustRequirements))
+			}
+			return nil
+		},
+	})
+}
+
+func TestTrustRequirementResourceTypeChange(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	typ := p.resourceSchema("enclave_trust_requirement").ValueType()
+	state, err := p.apply("enclave_trust_requirement", tftypes.NewValue(typ, nil), map[string]any{
+		"description": "portal login",
+		"user_authentication": map[string]any{
+			"authority": "portal",
+		},
+	})
+	if err != nil {
+		t.Fatalf("apply: %s", err)
+	}
+
+	tests := map[string]struct {
+		config  map[string]any
+		replace bool
+	}{
+		"same type": {
+			config: map[string]any{
+				"description": "azure login",
+				"user_authentication": map[string]any{
+					"authority":       "azure",
+					"azure_tenant_id": "tenant",
+				},
+			},
+		},
+		"no type": {
+			config: map[string]any{
+				"description": "portal login",
+			},
+			replace: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config, err := p.resourceConfig("enclave_trust_requirement", test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, requiresReplace, err := p.plan("enclave_trust_requirement", state, config)
+			if err != nil {
+				t.Fatalf("plan: %s", err)
+			}
+
+			if replace := len(requiresReplace) > 0; replace != test.replace {
+				t.Errorf("expected replace to be %v, got %v", test.replace, requiresReplace)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package enclave
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// How to upgrade state saved with an earlier version of a resource's schema. Each upgrade only has to get
+// state to the version after it, they're chained together so state from any version reaches the current one.
+type stateUpgrade struct {
+	// The schema of the version being upgraded from, kept as it was so older state can still be read.
+	// Only the types of its attributes and blocks matter.
+	priorSchema tfsdk.Schema
+
+	// Change the attributes of state saved with priorSchema to fit the next version's schema, nil if
+	// there's nothing to change. Attributes the next version doesn't have are dropped, and ones it
+	// added are null, afterwards.
+	upgrade func(attributes map[string]tftypes.Value) diag.Diagnostics
+}
+
+// The upgraders for a resource with a schema that's had a version for each of upgrades, so
+// upgrades[0] is from version 0. The schema's Version has to be len(upgrades).
+func stateUpgraders(upgrades ...stateUpgrade) map[int64]tfsdk.ResourceStateUpgrader {
+	upgraders := make(map[int64]tfsdk.ResourceStateUpgrader, len(upgrades))
+	for version := range upgrades {
+		version := version
+
+		upgraders[int64(version)] = tfsdk.ResourceStateUpgrader{
+			PriorSchema: &upgrades[version].priorSchema,
+			StateUpgrader: func(ctx context.Context, req tfsdk.UpgradeResourceStateRequest, resp *tfsdk.UpgradeResourceStateResponse) {
+				var attributes map[string]tftypes.Value
+				if err := req.State.Raw.As(&attributes); err != nil {
+					resp.Diagnostics.AddError("Unable to upgrade state", "Could not read the previously saved state: "+err.Error())
+					return
+				}
+
+				for next, upgrade := range upgrades[version:] {
+					if upgrade.upgrade != nil {
+						resp.Diagnostics.Append(upgrade.upgrade(attributes)...)
+						if resp.Diagnostics.HasError() {
+							return
+						}
+					}
+
+					nextSchema := resp.State.Schema
+					if version+next+1 < len(upgrades) {
+						nextSchema = upgrades[version+next+1].priorSchema
+					}
+					fitAttributes(ctx, nextSchema, attributes)
+				}
+
+				typ := resp.State.Schema.TerraformType(ctx)
+				if err := tftypes.ValidateValue(typ, attributes); err != nil {
+					resp.Diagnostics.AddError(
+						"Unable to upgrade state",
+						"The upgraded state doesn't match the current schema, this is a bug in the provider: "+err.Error(),
+					)
+					return
+				}
+
+				resp.State.Raw = tftypes.NewValue(typ, attributes)
+			},
+		}
+	}
+
+	return upgraders
+}
+
+// Drop attributes that aren't in schema, and add any missing ones as null
+func fitAttributes(ctx context.Context, schema tfsdk.Schema, attributes map[string]tftypes.Value) {
+	attributeTypes := schema.TerraformType(ctx).(tftypes.Object).AttributeTypes
+
+	for name := range attributes {
+		if _, ok := attributeTypes[name]; !ok {
+			delete(attributes, name)
+		}
+	}
+
+	for name, typ := range attributeTypes {
+		if _, ok := attributes[name]; !ok {
+			attributes[name] = tftypes.NewValue(typ, nil)
+		}
+	}
+}
+
+// An upgrade for the named attributes changing from lists to sets. A set is saved the same way as a
+// list so the elements carry over as they are, apart from any duplicates as a set can't hold them.
+func listsToSets(names ...string) func(map[string]tftypes.Value) diag.Diagnostics {
+	return func(attributes map[string]tftypes.Value) diag.Diagnostics {
+		for _, name := range names {
+			attributes[name] = listToSet(attributes[name])
+		}
+
+		return nil
+	}
+}
+
+func listToSet(list tftypes.Value) tftypes.Value {
+	typ := tftypes.Set{ElementType: list.Type().(tftypes.List).ElementType}
+	if list.IsNull() {
+		return tftypes.NewValue(typ, nil)
+	}
+
+	var elements []tftypes.Value
+	_ = list.As(&elements)
+
+	var unique []tftypes.Value
+	for _, element := range elements {
+		duplicate := false
+		for _, existing := range unique {
+			if element.Equal(existing) {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			unique = append(unique, element)
+		}
+	}
+
+	return tftypes.NewValue(typ, unique)
+}
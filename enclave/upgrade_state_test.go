@@ -0,0 +1,184 @@
+package enclave
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Every resource has to be able to upgrade state from each version of its schema to the current one
+func TestResourceUpgradeStateVersions(t *testing.T) {
+	p := newTestProvider(t, newMockApi(t), nil)
+
+	for resourceType, schema := range p.schema.ResourceSchemas {
+		for version := int64(0); version <= schema.Version; version++ {
+			if _, err := p.upgradeState(resourceType, version, `{}`); err != nil {
+				t.Errorf("%s: upgrading from version %d: %s", resourceType, version, err)
+			}
+		}
+	}
+}
+
+// Every resource implements UpgradeState, even when there's nothing to upgrade from yet, so the next
+// version only has to add to it
+func TestResourcesImplementUpgradeState(t *testing.T) {
+	ctx := context.Background()
+	p := New().(*provider)
+
+	resourceTypes, diags := p.GetResources(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	for name, resourceType := range resourceTypes {
+		resource, diags := resourceType.NewResource(ctx, p)
+		if diags.HasError() {
+			t.Fatalf("%s: %v", name, diags)
+		}
+
+		if _, ok := resource.(tfsdk.ResourceWithUpgradeState); !ok {
+			t.Errorf("%s doesn't implement tfsdk.ResourceWithUpgradeState", name)
+		}
+	}
+}
+
+// The versions of a made up resource: tags became a set in version 1, then name was renamed to title
+// and notes added in version 2
+var testSchemaVersions = []tfsdk.Schema{
+	{
+		Attributes: map[string]tfsdk.Attribute{
+			"name": {Type: types.StringType, Required: true},
+			"tags": {Type: types.ListType{ElemType: types.StringType}, Optional: true},
+		},
+	},
+	{
+		Version: 1,
+		Attributes: map[string]tfsdk.Attribute{
+			"name": {Type: types.StringType, Required: true},
+			"tags": {Type: types.SetType{ElemType: types.StringType}, Optional: true},
+		},
+	},
+	{
+		Version: 2,
+		Attributes: map[string]tfsdk.Attribute{
+			"title": {Type: types.StringType, Required: true},
+			"tags":  {Type: types.SetType{ElemType: types.StringType}, Optional: true},
+			"notes": {Type: types.StringType, Optional: true},
+		},
+	},
+}
+
+func testStateUpgraders() map[int64]tfsdk.ResourceStateUpgrader {
+	return stateUpgraders(
+		stateUpgrade{
+			priorSchema: testSchemaVersions[0],
+			upgrade:     listsToSets("tags"),
+		},
+		stateUpgrade{
+			priorSchema: testSchemaVersions[1],
+			upgrade: func(attributes map[string]tftypes.Value) diag.Diagnostics {
+				var name string
+				_ = attributes["name"].As(&name)
+				attributes["title"] = tftypes.NewValue(tftypes.String, name)
+				return nil
+			},
+		},
+	)
+}
+
+// Run an upgrader the way the framework does, with state read using its prior schema
+func runStateUpgrader(t *testing.T, upgrader tfsdk.ResourceStateUpgrader, attributes map[string]tftypes.Value) (map[string]any, diag.Diagnostics) {
+	t.Helper()
+
+	ctx := context.Background()
+	req := tfsdk.UpgradeResourceStateRequest{
+		State: &tfsdk.State{
+			Schema: *upgrader.PriorSchema,
+			Raw:    tftypes.NewValue(upgrader.PriorSchema.TerraformType(ctx), attributes),
+		},
+	}
+	resp := tfsdk.UpgradeResourceStateResponse{
+		State: tfsdk.State{Schema: testSchemaVersions[2]},
+	}
+
+	upgrader.StateUpgrader(ctx, req, &resp)
+	if resp.Diagnostics.HasError() {
+		return nil, resp.Diagnostics
+	}
+
+	return fromTerraformValue(resp.State.Raw).(map[string]any), nil
+}
+
+func TestStateUpgraders(t *testing.T) {
+	upgraders := testStateUpgraders()
+	if len(upgraders) != 2 {
+		t.Fatalf("expected an upgrader for versions 0 and 1, got %d", len(upgraders))
+	}
+
+	expected := map[string]any{
+		"title": "servers",
+		"tags":  []any{"linux", "web"},
+		"notes": nil,
+	}
+
+	// version 0 goes through both upgrades, dropping the duplicate tag on the way
+	upgraded, diags := runStateUpgrader(t, upgraders[0], map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "servers"),
+		"tags": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+			tftypes.NewValue(tftypes.String, "web"),
+			tftypes.NewValue(tftypes.String, "linux"),
+			tftypes.NewValue(tftypes.String, "web"),
+		}),
+	})
+	if diags.HasError() {
+		t.Fatalf("upgrading from version 0: %v", diags)
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("upgrading from version 0: expected %#v, got %#v", expected, upgraded)
+	}
+
+	upgraded, diags = runStateUpgrader(t, upgraders[1], map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "servers"),
+		"tags": tftypes.NewValue(tftypes.Set{ElementType: tftypes.String}, []tftypes.Value{
+			tftypes.NewValue(tftypes.String, "web"),
+			tftypes.NewValue(tftypes.String, "linux"),
+		}),
+	})
+	if diags.HasError() {
+		t.Fatalf("upgrading from version 1: %v", diags)
+	}
+	if !reflect.DeepEqual(expected, upgraded) {
+		t.Errorf("upgrading from version 1: expected %#v, got %#v", expected, upgraded)
+	}
+}
+
+func TestStateUpgradersMismatch(t *testing.T) {
+	// an upgrade that gets the type of an attribute wrong is an error rather than a panic
+	upgraders := stateUpgraders(
+		stateUpgrade{
+			priorSchema: testSchemaVersions[1],
+			upgrade: func(attributes map[string]tftypes.Value) diag.Diagnostics {
+				attributes["title"] = tftypes.NewValue(tftypes.Bool, true)
+				return nil
+			},
+		},
+	)
+
+	_, diags := runStateUpgrader(t, upgraders[0], map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "servers"),
+		"tags": tftypes.NewValue(tftypes.Set{ElementType: tftypes.String}, nil),
+	})
+	if !diags.HasError() {
+		t.Fatal("expected an error")
+	}
+
+	if summary := diags[0].Summary() + ": " + diags[0].Detail(); !regexp.MustCompile(`doesn't match the current schema`).MatchString(summary) {
+		t.Errorf("unexpected error: %s", summary)
+	}
+}
@@ -0,0 +1,186 @@
+package enclave
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Checks an acl protocol is one the api accepts, ignoring case
+type aclProtocolValidator struct{}
+
+func (v aclProtocolValidator) Description(_ context.Context) string {
+	return "must be one of: any, tcp, udp, icmp"
+}
+
+func (v aclProtocolValidator) MarkdownDescription(ctx context.Context) string {
+	return "must be one of: `any`, `tcp`, `udp`, `icmp`"
+}
+
+func (v aclProtocolValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var protocol types.String
+	diags := tfsdk.ValueAs(ctx, req.AttributeConfig, &protocol)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() || protocol.Null || protocol.Unknown {
+		return
+	}
+
+	if _, err := isValidProtocol(protocol.Value); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.AttributePath,
+			"Invalid ACL protocol",
+			fmt.Sprintf("%q is not a valid protocol, %s", protocol.Value, v.Description(ctx)),
+		)
+	}
+}
+
+// Checks a string is one of a set of values, ignoring case
+type oneOfValidator struct {
+	values []string
+}
+
+func (v oneOfValidator) Description(_ context.Context) string {
+	return "must be one of: " + strings.Join(v.values, ", ")
+}
+
+func (v oneOfValidator) MarkdownDescription(_ context.Context) string {
+	return "must be one of: `" + strings.Join(v.values, "`, `") + "`"
+}
+
+func (v oneOfValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	diags := tfsdk.ValueAs(ctx, req.AttributeConfig, &value)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() || value.Null || value.Unknown {
+		return
+	}
+
+	for _, allowed := range v.values {
+		if strings.EqualFold(value.Value, allowed) {
+			return
+		}
+	}
+
+	resp.Diagnostics.AddAttributeError(
+		req.AttributePath,
+		"Invalid value",
+		fmt.Sprintf("%q is not valid, %s", value.Value, v.Description(ctx)),
+	)
+}
+
+// Checks a string is a positive duration such as 30s or 5m
+type durationValidator struct{}
+
+func (v durationValidator) Description(_ context.Context) string {
+	return "must be a positive duration such as 30s or 5m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return "must be a positive duration such as `30s` or `5m`"
+}
+
+func (v durationValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var duration types.String
+	diags := tfsdk.ValueAs(ctx, req.AttributeConfig, &duration)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() || duration.Null || duration.Unknown {
+		return
+	}
+
+	if parsed, err := time.ParseDuration(duration.Value); err != nil || parsed <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.AttributePath,
+			"Invalid duration",
+			fmt.Sprintf("%q is not valid, %s", duration.Value, v.Description(ctx)),
+		)
+	}
+}
+
+// Checks acl ports are a single port or a range of ports, e.g. 80 or 8000-8080, and that the protocol
+// next to them has ports
+type aclPortsValidator struct{}
+
+func (v aclPortsValidator) Description(_ context.Context) string {
+	return "must be a port or a range of ports between 1 and 65535, e.g. 80 or 8000-8080, and can only be set for tcp and udp"
+}
+
+func (v aclPortsValidator) MarkdownDescription(ctx context.Context) string {
+	return "must be a port or a range of ports between 1 and 65535, e.g. `80` or `8000-8080`, and can only be set for `tcp` and `udp`"
+}
+
+func (v aclPortsValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var ports types.String
+	diags := tfsdk.ValueAs(ctx, req.AttributeConfig, &ports)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() || ports.Null || ports.Unknown {
+		return
+	}
+
+	if err := parsePortRange(ports.Value); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.AttributePath,
+			"Invalid ACL ports",
+			fmt.Sprintf("%q is not valid: %s, %s", ports.Value, err, v.Description(ctx)),
+		)
+		return
+	}
+
+	var protocol types.String
+	diags = req.Config.GetAttribute(ctx, req.AttributePath.WithoutLastStep().WithAttributeName("protocol"), &protocol)
+	resp.Diagnostics.Append(diags...)
+	if diags.HasError() || protocol.Null || protocol.Unknown {
+		return
+	}
+
+	// the protocol validator reports protocols that aren't valid at all
+	switch strings.ToLower(protocol.Value) {
+	case "any", "icmp":
+		resp.Diagnostics.AddAttributeError(
+			req.AttributePath,
+			"Invalid ACL ports",
+			fmt.Sprintf("Ports can't be set for the %s protocol, %s", strings.ToLower(protocol.Value), v.Description(ctx)),
+		)
+	}
+}
+
+func parsePortRange(ports string) error {
+	from, to, isRange := strings.Cut(ports, "-")
+
+	start, err := parsePort(from)
+	if err != nil {
+		return err
+	}
+
+	if !isRange {
+		return nil
+	}
+
+	end, err := parsePort(to)
+	if err != nil {
+		return err
+	}
+
+	if start > end {
+		return fmt.Errorf("the range starts after it ends")
+	}
+
+	return nil
+}
+
+func parsePort(port string) (int, error) {
+	value, err := strconv.Atoi(port)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", port)
+	}
+
+	if value < 1 || value > 65535 {
+		return 0, fmt.Errorf("%d is out of range", value)
+	}
+
+	return value, nil
+}
@@ -33,7 +33,7 @@ provider "enclave" {
   organisation_id = "<orgId>"
 }
 
-resource "enclave_policy_acl" "ssh" {
+data "enclave_policy_acl" "ssh" {
   protocol = "tcp"
   ports = "22"
 }
@@ -43,7 +43,7 @@ resource "enclave_policy" "dev_to_ec2" {
   sender_tags   = ["developer"]
   receiver_tags = ["aws-ec2"]
   acl = [
-    enclave_policy_acl.ssh,
+    data.enclave_policy_acl.ssh,
   ]
 }
 
@@ -23,7 +23,7 @@ provider "enclave" {
   organisation_id = "<orgId>"
 }
 
-resource "enclave_policy_acl" "sql" {
+data "enclave_policy_acl" "sql" {
   protocol = "tcp"
   ports = "1433"
 }
@@ -33,7 +33,7 @@ resource "enclave_policy" "devs_to_db" {
   sender_tags   = ["developer"]
   receiver_tags = ["database"]
   acl = [
-    enclave_policy_acl.sql
+    data.enclave_policy_acl.sql
   ]
 }
 